@@ -42,23 +42,65 @@ func main() {
 	log.Println("Database initialized successfully")
 
 	// Create embedding client.
-	embClient := core.NewEmbeddingClient(
-		cfg.OpenAIAPIKey,
-		cfg.OpenAIEmbeddingModel,
-		cfg.OpenAIEmbeddingDimensions,
-	)
-	if cfg.OpenAIAPIKey == "" {
-		log.Println("WARNING: OpenAI API key is not set. Embedding features will be disabled.")
-	} else {
-		log.Println("Embedding client initialized")
+	embClient := core.NewEmbedder(cfg)
+	switch embClient.(type) {
+	case *core.LocalEmbedder:
+		log.Printf("Embedding client initialized (local, %d dimensions)", cfg.OpenAIEmbeddingDimensions)
+	case core.NoopEmbedder:
+		log.Println("WARNING: No embedding provider configured. Scans will use keyword search only.")
+		log.Println("         Set OPENAI_API_KEY, or EMBEDDING_PROVIDER=local for the offline embedder.")
+	default:
+		log.Printf("Embedding client initialized (%s, model %s)", cfg.OpenAIBaseURL, cfg.OpenAIEmbeddingModel)
+	}
+
+	// The local embedder is cheaper to recompute than to look up; only cache the remote provider.
+	if _, remote := embClient.(*core.EmbeddingClient); remote && cfg.EmbeddingCacheEnabled {
+		embClient = core.NewCachedEmbedder(database, embClient, cfg.EmbeddingCacheSize)
+		log.Printf("Embedding cache enabled (%d in-memory entries)", cfg.EmbeddingCacheSize)
 	}
 
+	// Load task embeddings into the in-memory vector index.
+	index := core.NewVectorIndex(embClient.Model(), embClient.Dimensions())
+	if err := index.Load(database); err != nil {
+		log.Fatalf("Failed to load vector index: %v", err)
+	}
+	log.Printf("Vector index loaded (%d vectors, %d awaiting re-embedding)", index.Len(), index.Incompatible())
+
 	// Start background cleanup goroutine.
 	go core.StartCleanupTicker(database, cfg)
 	log.Println("Background cleanup ticker started (1h interval)")
 
+	// Re-embed tasks left over from a previous embedding model or dimension count.
+	go core.StartEmbeddingMigrator(database, embClient, index, cfg)
+
+	// Retry task embeddings that failed at registration or update time.
+	go core.StartEmbeddingRetryWorker(database, embClient, index, cfg)
+
+	// Match new and changed beacons against standing radar searches.
+	go core.StartStandingMatchWorker(database, embClient, index, cfg)
+
+	// Learn from conversation outcomes to re-rank scan results.
+	var priors *core.OutcomePriors
+	if cfg.OutcomeRerankEnabled {
+		priors = core.NewOutcomePriors(cfg.OutcomeRerankWeight)
+		go core.StartOutcomePriorsJob(database, priors, cfg)
+		log.Printf("Outcome re-ranking enabled (weight %.2f)", cfg.OutcomeRerankWeight)
+	}
+
+	// Contact cards are stored encrypted; without a key the exchange is disabled.
+	var vault *core.ContactVault
+	if cfg.ContactEncryptionKey != "" {
+		vault, err = core.NewContactVault(cfg.ContactEncryptionKey)
+		if err != nil {
+			log.Fatalf("Invalid CONTACT_ENCRYPTION_KEY: %v", err)
+		}
+		log.Println("Contact exchange enabled")
+	} else {
+		log.Println("WARNING: CONTACT_ENCRYPTION_KEY not set. Contact exchange is disabled.")
+	}
+
 	// Setup router.
-	router := api.SetupRouter(database, cfg, embClient)
+	router := api.SetupRouter(database, cfg, embClient, index, priors, vault)
 
 	// Start server.
 	addr := ":" + cfg.Port
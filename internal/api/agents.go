@@ -1,8 +1,11 @@
 package api
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strings"
@@ -31,10 +34,64 @@ type TaskRequest struct {
 	Type     string   `json:"type" binding:"required"`
 	Title    string   `json:"title" binding:"required"`
 	Keywords []string `json:"keywords"`
+	// Attributes holds optional structured constraints (location, remote, salary, ...),
+	// validated against the task type's schema. Scans can filter on them.
+	Attributes map[string]interface{} `json:"attributes"`
+	// Geo optionally locates the task for radius matching.
+	Geo *GeoRequest `json:"geo"`
+	// Standing registers a radar task as a standing search: new matching beacons
+	// arrive as new_match notifications in the heartbeat.
+	Standing bool `json:"standing"`
+	// StandingFilters are the scan filters (see ScanRequest.Filters) a standing
+	// search applies to new beacons.
+	StandingFilters map[string]interface{} `json:"standing_filters"`
+}
+
+// GeoRequest locates a task by coordinates, or by a place name looked up in the
+// built-in gazetteer. With RadiusKm > 0, only counterparts within that distance match.
+type GeoRequest struct {
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+	Place     string   `json:"place"`
+	RadiusKm  float64  `json:"radius_km"`
+}
+
+// taskGeo is a resolved task location as stored in the tasks table.
+type taskGeo struct {
+	Latitude, Longitude *float64
+	RadiusKm            float64
+	Place               string
+}
+
+// resolveTaskGeo validates a GeoRequest. Explicit coordinates take precedence over
+// the place name, which is then kept as a label. A nil or empty request clears the location.
+func resolveTaskGeo(g *GeoRequest) (taskGeo, error) {
+	if g == nil || (g.Latitude == nil && g.Longitude == nil && g.Place == "") {
+		return taskGeo{}, nil
+	}
+	if g.RadiusKm < 0 {
+		return taskGeo{}, errors.New("radius_km must not be negative")
+	}
+
+	geo := taskGeo{Latitude: g.Latitude, Longitude: g.Longitude, RadiusKm: g.RadiusKm, Place: g.Place}
+	if g.Latitude == nil || g.Longitude == nil {
+		if g.Latitude != nil || g.Longitude != nil {
+			return taskGeo{}, errors.New("latitude and longitude must be given together")
+		}
+		p, ok := core.ResolvePlace(g.Place)
+		if !ok {
+			return taskGeo{}, fmt.Errorf("unknown place %q; pass latitude and longitude instead", g.Place)
+		}
+		geo.Latitude, geo.Longitude = &p.Lat, &p.Lon
+	}
+	if *geo.Latitude < -90 || *geo.Latitude > 90 || *geo.Longitude < -180 || *geo.Longitude > 180 {
+		return taskGeo{}, errors.New("latitude must be within [-90, 90] and longitude within [-180, 180]")
+	}
+	return geo, nil
 }
 
 // RegisterAgent handles POST /api/v1/agents/register.
-func RegisterAgent(database *sql.DB, cfg *config.Config, embClient *core.EmbeddingClient) gin.HandlerFunc {
+func RegisterAgent(database *sql.DB, cfg *config.Config, embClient core.Embedder, index *core.VectorIndex) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req RegisterRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -54,6 +111,34 @@ func RegisterAgent(database *sql.DB, cfg *config.Config, embClient *core.Embeddi
 				})
 				return
 			}
+			if (t.Standing || len(t.StandingFilters) > 0) && t.Mode != "radar" {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":   "invalid_standing",
+					"message": "Only radar tasks can be standing searches",
+				})
+				return
+			}
+			if err := core.ValidateAttributeFilters(t.StandingFilters); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":   "invalid_filters",
+					"message": "Task " + t.TaskID + ": " + err.Error(),
+				})
+				return
+			}
+			if err := core.ValidateTaskAttributes(t.Type, t.Attributes); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":   "invalid_attributes",
+					"message": "Task " + t.TaskID + ": " + err.Error(),
+				})
+				return
+			}
+			if _, err := resolveTaskGeo(t.Geo); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":   "invalid_geo",
+					"message": "Task " + t.TaskID + ": " + err.Error(),
+				})
+				return
+			}
 		}
 
 		now := time.Now().UTC()
@@ -136,15 +221,20 @@ func RegisterAgent(database *sql.DB, cfg *config.Config, embClient *core.Embeddi
 			Mode       string `json:"mode"`
 		}
 		var taskMappings []taskMapping
+		var embTaskIDs []string
+		var embKeywords [][]string
+		var standingTaskIDs []string
 
 		for _, t := range req.Tasks {
 			taskID := core.GenerateMD5(agentID, t.TaskID)
 			keywordsJSON, _ := json.Marshal(t.Keywords)
+			geo, _ := resolveTaskGeo(t.Geo)
 
 			_, err = database.Exec(
-				`INSERT INTO tasks (id, agent_id, task_id, mode, type, title, keywords, status, created_at)
-				 VALUES (?, ?, ?, ?, ?, ?, ?, 'active', ?)`,
-				taskID, agentID, t.TaskID, t.Mode, t.Type, t.Title, string(keywordsJSON), createdAt,
+				`INSERT INTO tasks (id, agent_id, task_id, mode, type, title, keywords, attributes, latitude, longitude, radius_km, place, status, standing, standing_filters, created_at)
+				 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 'active', ?, ?, ?)`,
+				taskID, agentID, t.TaskID, t.Mode, t.Type, t.Title, string(keywordsJSON), attributesJSON(t.Attributes),
+				geo.Latitude, geo.Longitude, geo.RadiusKm, geo.Place, t.Standing, attributesJSON(t.StandingFilters), createdAt,
 			)
 			if err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{
@@ -161,22 +251,20 @@ func RegisterAgent(database *sql.DB, cfg *config.Config, embClient *core.Embeddi
 				Mode:       t.Mode,
 			})
 
-			// Compute embedding from keywords.
-			if len(t.Keywords) > 0 {
-				keywordsText := strings.Join(t.Keywords, " ")
-				embedding, embErr := embClient.GetEmbedding(keywordsText)
-				if embErr != nil {
-					log.Printf("WARNING: Failed to compute embedding for task %s: %v", t.TaskID, embErr)
-					continue
-				}
-				embBytes := core.EmbeddingToBytes(embedding)
-				_, _ = database.Exec(
-					"INSERT OR REPLACE INTO task_embeddings (task_id, embedding) VALUES (?, ?)",
-					taskID, embBytes,
-				)
+			embTaskIDs = append(embTaskIDs, taskID)
+			embKeywords = append(embKeywords, t.Keywords)
+			if t.Standing {
+				standingTaskIDs = append(standingTaskIDs, taskID)
 			}
 		}
 
+		// Compute all task embeddings in a single batch call.
+		if err := storeTaskEmbeddings(c.Request.Context(), database, embClient, index, embTaskIDs, embKeywords); err != nil {
+			log.Printf("WARNING: Failed to compute embeddings for agent %s (queued for retry): %v", agentID, err)
+		}
+		core.QueueStandingMatches(database, embTaskIDs)
+		core.QueueStandingBackfills(database, standingTaskIDs)
+
 		// Increment registration count.
 		_, _ = database.Exec(
 			"UPDATE registration_limits SET daily_count = daily_count + 1 WHERE ip_mac_hash = ?",
@@ -193,7 +281,7 @@ func RegisterAgent(database *sql.DB, cfg *config.Config, embClient *core.Embeddi
 }
 
 // CreateTask handles POST /api/v1/agents/tasks.
-func CreateTask(database *sql.DB, embClient *core.EmbeddingClient) gin.HandlerFunc {
+func CreateTask(database *sql.DB, cfg *config.Config, embClient core.Embedder, index *core.VectorIndex) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		agent, ok := getAgent(c)
 		if !ok {
@@ -220,6 +308,35 @@ func CreateTask(database *sql.DB, embClient *core.EmbeddingClient) gin.HandlerFu
 			})
 			return
 		}
+		if (req.Standing || len(req.StandingFilters) > 0) && req.Mode != "radar" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_standing",
+				"message": "Only radar tasks can be standing searches",
+			})
+			return
+		}
+		if err := core.ValidateAttributeFilters(req.StandingFilters); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_filters",
+				"message": err.Error(),
+			})
+			return
+		}
+		if err := core.ValidateTaskAttributes(req.Type, req.Attributes); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_attributes",
+				"message": err.Error(),
+			})
+			return
+		}
+		geo, err := resolveTaskGeo(req.Geo)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_geo",
+				"message": err.Error(),
+			})
+			return
+		}
 
 		// Check daily task creation limit (10 per agent per day).
 		today := time.Now().UTC().Format("2006-01-02")
@@ -252,10 +369,11 @@ func CreateTask(database *sql.DB, embClient *core.EmbeddingClient) gin.HandlerFu
 		now := time.Now().UTC().Format(time.RFC3339)
 		keywordsJSON, _ := json.Marshal(req.Keywords)
 
-		_, err := database.Exec(
-			`INSERT INTO tasks (id, agent_id, task_id, mode, type, title, keywords, status, created_at, updated_at)
-			 VALUES (?, ?, ?, ?, ?, ?, ?, 'active', ?, ?)`,
-			taskID, agent.ID, req.TaskID, req.Mode, req.Type, req.Title, string(keywordsJSON), now, now,
+		_, err = database.Exec(
+			`INSERT INTO tasks (id, agent_id, task_id, mode, type, title, keywords, attributes, latitude, longitude, radius_km, place, status, standing, standing_filters, created_at, updated_at)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 'active', ?, ?, ?, ?)`,
+			taskID, agent.ID, req.TaskID, req.Mode, req.Type, req.Title, string(keywordsJSON), attributesJSON(req.Attributes),
+			geo.Latitude, geo.Longitude, geo.RadiusKm, geo.Place, req.Standing, attributesJSON(req.StandingFilters), now, now,
 		)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -266,18 +384,12 @@ func CreateTask(database *sql.DB, embClient *core.EmbeddingClient) gin.HandlerFu
 		}
 
 		// Compute embedding.
-		if len(req.Keywords) > 0 {
-			keywordsText := strings.Join(req.Keywords, " ")
-			embedding, embErr := embClient.GetEmbedding(keywordsText)
-			if embErr != nil {
-				log.Printf("WARNING: Failed to compute embedding for task %s: %v", req.TaskID, embErr)
-			} else {
-				embBytes := core.EmbeddingToBytes(embedding)
-				_, _ = database.Exec(
-					"INSERT OR REPLACE INTO task_embeddings (task_id, embedding) VALUES (?, ?)",
-					taskID, embBytes,
-				)
-			}
+		if err := storeTaskEmbeddings(c.Request.Context(), database, embClient, index, []string{taskID}, [][]string{req.Keywords}); err != nil {
+			log.Printf("WARNING: Failed to compute embedding for task %s (queued for retry): %v", req.TaskID, err)
+		}
+		core.QueueStandingMatches(database, []string{taskID})
+		if req.Standing {
+			core.QueueStandingBackfills(database, []string{taskID})
 		}
 
 		c.JSON(http.StatusCreated, gin.H{
@@ -285,19 +397,76 @@ func CreateTask(database *sql.DB, embClient *core.EmbeddingClient) gin.HandlerFu
 			"platform_id": taskID,
 			"title":       req.Title,
 			"mode":        req.Mode,
+			"standing":    req.Standing,
 		})
 	}
 }
 
+// storeTaskEmbeddings computes embeddings for the keywords of the given tasks in a
+// single batch call and stores them. taskIDs and keywords are index-aligned;
+// tasks without keywords lose any embedding they had, so they stop matching on
+// keywords they no longer have. On failure the tasks are queued for the
+// background retry worker.
+func storeTaskEmbeddings(ctx context.Context, database *sql.DB, embClient core.Embedder, index *core.VectorIndex, taskIDs []string, keywords [][]string) error {
+	var ids, texts []string
+	for i, kw := range keywords {
+		if len(kw) == 0 {
+			core.DeleteTaskEmbedding(database, index, taskIDs[i])
+			continue
+		}
+		ids = append(ids, taskIDs[i])
+		texts = append(texts, strings.Join(kw, " "))
+	}
+	if len(texts) == 0 {
+		return nil
+	}
+
+	embeddings, err := embClient.GetEmbeddings(ctx, texts)
+	if errors.Is(err, core.ErrEmbeddingNotConfigured) {
+		// Keyword-only deployment: tasks are matched through the lexical index.
+		return nil
+	}
+	if err != nil {
+		core.EnqueueTaskEmbeddings(database, ids, err)
+		return err
+	}
+
+	for i, embedding := range embeddings {
+		if err := core.SaveTaskEmbedding(database, index, ids[i], embedding, embClient.Model()); err != nil {
+			core.EnqueueTaskEmbeddings(database, ids[i:], err)
+			return err
+		}
+	}
+	core.DequeueTaskEmbeddings(database, ids)
+
+	return nil
+}
+
+// attributesJSON encodes task attributes (or standing filters) for storage. Missing attributes are stored as {}.
+func attributesJSON(attrs map[string]interface{}) string {
+	if len(attrs) == 0 {
+		return "{}"
+	}
+	b, _ := json.Marshal(attrs)
+	return string(b)
+}
+
 // UpdateTaskRequest is the body for PUT /api/v1/agents/tasks/:taskId.
 type UpdateTaskRequest struct {
 	Title    string   `json:"title"`
 	Keywords []string `json:"keywords"`
 	Status   string   `json:"status"`
+	Standing *bool    `json:"standing"`
+	// StandingFilters, when present, replace the standing search's filters; {} clears them.
+	StandingFilters map[string]interface{} `json:"standing_filters"`
+	// Attributes, when present, replace the task's attributes; {} clears them.
+	Attributes map[string]interface{} `json:"attributes"`
+	// Geo, when present, replaces the task's location; {} clears it.
+	Geo *GeoRequest `json:"geo"`
 }
 
 // UpdateTask handles PUT /api/v1/agents/tasks/:taskId.
-func UpdateTask(database *sql.DB, embClient *core.EmbeddingClient) gin.HandlerFunc {
+func UpdateTask(database *sql.DB, cfg *config.Config, embClient core.Embedder, index *core.VectorIndex) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		agent, ok := getAgent(c)
 		if !ok {
@@ -313,12 +482,15 @@ func UpdateTask(database *sql.DB, embClient *core.EmbeddingClient) gin.HandlerFu
 		// Verify the task belongs to the authenticated agent.
 		var existingTask dbpkg.Task
 		err := database.QueryRow(
-			"SELECT id, agent_id, task_id, mode, type, title, keywords, status, created_at FROM tasks WHERE task_id = ? AND agent_id = ?",
+			`SELECT id, agent_id, task_id, mode, type, title, keywords, attributes, status, standing, standing_filters,
+			        latitude, longitude, radius_km, place, created_at
+			 FROM tasks WHERE task_id = ? AND agent_id = ?`,
 			taskID, agent.ID,
 		).Scan(
 			&existingTask.ID, &existingTask.AgentID, &existingTask.TaskID,
 			&existingTask.Mode, &existingTask.Type, &existingTask.Title,
-			&existingTask.Keywords, &existingTask.Status, &existingTask.CreatedAt,
+			&existingTask.Keywords, &existingTask.Attributes, &existingTask.Status, &existingTask.Standing, &existingTask.StandingFilters,
+			&existingTask.Latitude, &existingTask.Longitude, &existingTask.RadiusKm, &existingTask.Place, &existingTask.CreatedAt,
 		)
 		if err == sql.ErrNoRows {
 			c.JSON(http.StatusNotFound, gin.H{
@@ -364,6 +536,59 @@ func UpdateTask(database *sql.DB, embClient *core.EmbeddingClient) gin.HandlerFu
 			}
 		}
 
+		if req.Standing != nil {
+			if *req.Standing && existingTask.Mode != "radar" {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":   "invalid_standing",
+					"message": "Only radar tasks can be standing searches",
+				})
+				return
+			}
+			existingTask.Standing = *req.Standing
+		}
+
+		if req.StandingFilters != nil {
+			if existingTask.Mode != "radar" {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":   "invalid_standing",
+					"message": "Only radar tasks can be standing searches",
+				})
+				return
+			}
+			if err := core.ValidateAttributeFilters(req.StandingFilters); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":   "invalid_filters",
+					"message": err.Error(),
+				})
+				return
+			}
+			existingTask.StandingFilters = attributesJSON(req.StandingFilters)
+		}
+
+		if req.Attributes != nil {
+			if err := core.ValidateTaskAttributes(existingTask.Type, req.Attributes); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":   "invalid_attributes",
+					"message": err.Error(),
+				})
+				return
+			}
+			existingTask.Attributes = attributesJSON(req.Attributes)
+		}
+
+		if req.Geo != nil {
+			geo, err := resolveTaskGeo(req.Geo)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":   "invalid_geo",
+					"message": err.Error(),
+				})
+				return
+			}
+			existingTask.Latitude, existingTask.Longitude = geo.Latitude, geo.Longitude
+			existingTask.RadiusKm, existingTask.Place = geo.RadiusKm, geo.Place
+		}
+
 		keywordsChanged := false
 		if req.Keywords != nil {
 			keywordsJSON, _ := json.Marshal(req.Keywords)
@@ -373,8 +598,11 @@ func UpdateTask(database *sql.DB, embClient *core.EmbeddingClient) gin.HandlerFu
 
 		now := time.Now().UTC().Format(time.RFC3339)
 		_, err = database.Exec(
-			"UPDATE tasks SET title = ?, keywords = ?, status = ?, updated_at = ? WHERE id = ?",
-			existingTask.Title, existingTask.Keywords, existingTask.Status, now, existingTask.ID,
+			`UPDATE tasks SET title = ?, keywords = ?, attributes = ?, status = ?, standing = ?, standing_filters = ?,
+			        latitude = ?, longitude = ?, radius_km = ?, place = ?, updated_at = ?
+			 WHERE id = ?`,
+			existingTask.Title, existingTask.Keywords, existingTask.Attributes, existingTask.Status, existingTask.Standing, existingTask.StandingFilters,
+			existingTask.Latitude, existingTask.Longitude, existingTask.RadiusKm, existingTask.Place, now, existingTask.ID,
 		)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -389,36 +617,40 @@ func UpdateTask(database *sql.DB, embClient *core.EmbeddingClient) gin.HandlerFu
 		if req.Status != "" && req.Status != oldStatus {
 			if req.Status == "paused" || req.Status == "completed" {
 				// Remove embedding — task no longer participates in matching.
-				_, _ = database.Exec("DELETE FROM task_embeddings WHERE task_id = ?", existingTask.ID)
+				core.DeleteTaskEmbedding(database, index, existingTask.ID)
 			} else if req.Status == "active" && (oldStatus == "paused" || oldStatus == "completed") {
 				// Re-entering active: regenerate embedding from keywords.
 				var kw []string
 				_ = json.Unmarshal([]byte(existingTask.Keywords), &kw)
-				if len(kw) > 0 {
-					keywordsText := strings.Join(kw, " ")
-					embedding, embErr := embClient.GetEmbedding(keywordsText)
-					if embErr == nil {
-						embBytes := core.EmbeddingToBytes(embedding)
-						_, _ = database.Exec(
-							"INSERT OR REPLACE INTO task_embeddings (task_id, embedding) VALUES (?, ?)",
-							existingTask.ID, embBytes,
-						)
-					}
+				if err := storeTaskEmbeddings(c.Request.Context(), database, embClient, index, []string{existingTask.ID}, [][]string{kw}); err != nil {
+					log.Printf("WARNING: Failed to compute embedding for task %s (queued for retry): %v", existingTask.TaskID, err)
 				}
 				keywordsChanged = false // Already handled
 			}
 		}
 
 		// Recompute embedding if keywords changed and task is active.
-		if keywordsChanged && len(req.Keywords) > 0 && existingTask.Status == "active" {
-			keywordsText := strings.Join(req.Keywords, " ")
-			embedding, embErr := embClient.GetEmbedding(keywordsText)
-			if embErr == nil {
-				embBytes := core.EmbeddingToBytes(embedding)
-				_, _ = database.Exec(
-					"INSERT OR REPLACE INTO task_embeddings (task_id, embedding) VALUES (?, ?)",
-					existingTask.ID, embBytes,
-				)
+		if keywordsChanged && existingTask.Status == "active" {
+			if err := storeTaskEmbeddings(c.Request.Context(), database, embClient, index, []string{existingTask.ID}, [][]string{req.Keywords}); err != nil {
+				log.Printf("WARNING: Failed to compute embedding for task %s (queued for retry): %v", existingTask.TaskID, err)
+			}
+		}
+
+		// The index filters on attributes and location; pick up new ones for a
+		// task that is otherwise unchanged.
+		if existingTask.Status == "active" && (req.Attributes != nil || req.Geo != nil) {
+			if err := index.Refresh(database, existingTask.ID); err != nil {
+				log.Printf("WARNING: Failed to refresh index entry for task %s: %v", existingTask.TaskID, err)
+			}
+		}
+
+		if existingTask.Status == "active" {
+			core.QueueStandingMatches(database, []string{existingTask.ID})
+			// A radar switched to standing, reactivated, or given new keywords or
+			// filters picks up the beacons that already match; pairs it was
+			// notified of before are not repeated.
+			if existingTask.Standing {
+				core.QueueStandingBackfills(database, []string{existingTask.ID})
 			}
 		}
 
@@ -429,7 +661,8 @@ func UpdateTask(database *sql.DB, embClient *core.EmbeddingClient) gin.HandlerFu
 }
 
 // GetMe handles GET /api/v1/agents/me.
-func GetMe(database *sql.DB) gin.HandlerFunc {
+// Each task includes its embedding status, so the agent knows whether it is discoverable.
+func GetMe(database *sql.DB, embClient core.Embedder) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		agent, ok := getAgent(c)
 		if !ok {
@@ -442,7 +675,9 @@ func GetMe(database *sql.DB) gin.HandlerFunc {
 
 		// Fetch tasks for this agent.
 		rows, err := database.Query(
-			"SELECT id, agent_id, task_id, mode, type, title, keywords, status, created_at, updated_at FROM tasks WHERE agent_id = ?",
+			`SELECT id, agent_id, task_id, mode, type, title, keywords, attributes, status, standing, standing_filters,
+			        latitude, longitude, radius_km, place, created_at, updated_at
+			 FROM tasks WHERE agent_id = ?`,
 			agent.ID,
 		)
 		if err != nil {
@@ -454,17 +689,33 @@ func GetMe(database *sql.DB) gin.HandlerFunc {
 		}
 		defer rows.Close()
 
-		var tasks []dbpkg.Task
+		keywordOnly := embClient.Status().Mode == core.EmbeddingModeKeywordOnly
+		embStatuses, err := core.GetTaskEmbeddingStatuses(database, agent.ID, embClient.Model(), embClient.Dimensions(), keywordOnly)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"message": "Failed to fetch embedding status",
+			})
+			return
+		}
+
+		type taskResponse struct {
+			dbpkg.Task
+			Embedding core.TaskEmbeddingStatus `json:"embedding"`
+		}
+
+		var tasks []taskResponse
 		for rows.Next() {
 			var t dbpkg.Task
-			if err := rows.Scan(&t.ID, &t.AgentID, &t.TaskID, &t.Mode, &t.Type, &t.Title, &t.Keywords, &t.Status, &t.CreatedAt, &t.UpdatedAt); err != nil {
+			if err := rows.Scan(&t.ID, &t.AgentID, &t.TaskID, &t.Mode, &t.Type, &t.Title, &t.Keywords, &t.Attributes, &t.Status, &t.Standing, &t.StandingFilters,
+				&t.Latitude, &t.Longitude, &t.RadiusKm, &t.Place, &t.CreatedAt, &t.UpdatedAt); err != nil {
 				continue
 			}
-			tasks = append(tasks, t)
+			tasks = append(tasks, taskResponse{Task: t, Embedding: embStatuses[t.ID]})
 		}
 
 		if tasks == nil {
-			tasks = []dbpkg.Task{}
+			tasks = []taskResponse{}
 		}
 
 		hb := ""
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"agentsocial/internal/core"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestUpdateTaskKeywords(t *testing.T) {
+	tests := []struct {
+		name         string
+		keywords     []string
+		wantEmbedded bool
+	}{
+		{"new keywords are re-embedded", []string{"rust", "systems"}, true},
+		{"clearing keywords drops the embedding", []string{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			agentID, token := s.register("a", TaskRequest{
+				TaskID: "t1", Mode: "beacon", Type: "hiring", Title: "Go dev", Keywords: []string{"golang", "backend"},
+			})
+			id := s.taskID(agentID, "t1")
+			before, ok := s.index.Vector(id)
+			if !ok {
+				t.Fatal("task not embedded at registration")
+			}
+
+			code, resp := s.do(http.MethodPut, "/api/v1/agents/tasks/t1", token, gin.H{"keywords": tt.keywords})
+			if code != http.StatusOK {
+				t.Fatalf("update: %d %v", code, resp)
+			}
+
+			var stored int
+			if err := s.db.QueryRow("SELECT COUNT(*) FROM task_embeddings WHERE task_id = ?", id).Scan(&stored); err != nil {
+				t.Fatal(err)
+			}
+			after, indexed := s.index.Vector(id)
+			if (stored == 1) != tt.wantEmbedded || indexed != tt.wantEmbedded {
+				t.Fatalf("stored, indexed = %d, %v; want embedded %v", stored, indexed, tt.wantEmbedded)
+			}
+			if indexed && core.CosineSimilarity(before, after) > 0.99 {
+				t.Error("vector not recomputed from the new keywords")
+			}
+		})
+	}
+}
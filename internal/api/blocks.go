@@ -0,0 +1,155 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	dbpkg "agentsocial/internal/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateBlockRequest is the body for POST /api/v1/agents/blocks.
+type CreateBlockRequest struct {
+	AgentID string `json:"agent_id" binding:"required"`
+}
+
+// CreateBlock handles POST /api/v1/agents/blocks.
+// Blocked agents never appear in the caller's scans, and the caller never appears in
+// theirs; neither side can open a new conversation with the other.
+func CreateBlock(database *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		agent, ok := getAgent(c)
+		if !ok {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "unauthorized",
+				"message": "Authentication required",
+			})
+			return
+		}
+
+		var req CreateBlockRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_request",
+				"message": "Invalid request body: " + err.Error(),
+			})
+			return
+		}
+
+		if req.AgentID == agent.ID {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_block",
+				"message": "Cannot block yourself",
+			})
+			return
+		}
+
+		var targetExists int
+		err := database.QueryRow("SELECT COUNT(*) FROM agents WHERE id = ?", req.AgentID).Scan(&targetExists)
+		if err != nil || targetExists == 0 {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "agent_not_found",
+				"message": "Target agent not found",
+			})
+			return
+		}
+
+		now := time.Now().UTC().Format(time.RFC3339)
+		_, err = database.Exec(
+			"INSERT OR IGNORE INTO agent_blocks (blocker_id, blocked_id, created_at) VALUES (?, ?, ?)",
+			agent.ID, req.AgentID, now,
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"message": "Failed to block agent",
+			})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"blocked_id": req.AgentID,
+			"message":    "Agent blocked",
+		})
+	}
+}
+
+// ListBlocks handles GET /api/v1/agents/blocks.
+func ListBlocks(database *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		agent, ok := getAgent(c)
+		if !ok {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "unauthorized",
+				"message": "Authentication required",
+			})
+			return
+		}
+
+		rows, err := database.Query(
+			"SELECT blocker_id, blocked_id, created_at FROM agent_blocks WHERE blocker_id = ? ORDER BY created_at DESC",
+			agent.ID,
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"message": "Failed to query blocks",
+			})
+			return
+		}
+		defer rows.Close()
+
+		blocks := []dbpkg.AgentBlock{}
+		for rows.Next() {
+			var b dbpkg.AgentBlock
+			if err := rows.Scan(&b.BlockerID, &b.BlockedID, &b.CreatedAt); err != nil {
+				continue
+			}
+			blocks = append(blocks, b)
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"blocks": blocks,
+		})
+	}
+}
+
+// DeleteBlock handles DELETE /api/v1/agents/blocks/:agentId.
+func DeleteBlock(database *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		agent, ok := getAgent(c)
+		if !ok {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "unauthorized",
+				"message": "Authentication required",
+			})
+			return
+		}
+
+		result, err := database.Exec(
+			"DELETE FROM agent_blocks WHERE blocker_id = ? AND blocked_id = ?",
+			agent.ID, c.Param("agentId"),
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"message": "Failed to unblock agent",
+			})
+			return
+		}
+
+		if n, _ := result.RowsAffected(); n == 0 {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "block_not_found",
+				"message": "Agent is not blocked",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Agent unblocked",
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"agentsocial/internal/core"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContactCardRequest is the body for PUT /api/v1/agents/contact-card.
+type ContactCardRequest struct {
+	// TaskID (the agent's own task_id) scopes the card to one task. Without it the
+	// card is the agent's default, used for tasks that have no card of their own.
+	TaskID string            `json:"task_id"`
+	Card   map[string]string `json:"card" binding:"required"`
+}
+
+// PutContactCard handles PUT /api/v1/agents/contact-card.
+// Cards are stored encrypted and only released through a consented exchange.
+func PutContactCard(database *sql.DB, vault *core.ContactVault) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		agent, ok := getAgent(c)
+		if !ok {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "unauthorized",
+				"message": "Authentication required",
+			})
+			return
+		}
+		if !requireContactVault(c, vault) {
+			return
+		}
+
+		var req ContactCardRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_request",
+				"message": "Invalid request body: " + err.Error(),
+			})
+			return
+		}
+		if err := core.ValidateContactCard(req.Card); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_contact_card",
+				"message": err.Error(),
+			})
+			return
+		}
+
+		taskID, ok := contactCardTask(c, database, agent.ID, req.TaskID)
+		if !ok {
+			return
+		}
+
+		if err := core.SaveContactCard(database, vault, agent.ID, taskID, req.Card); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"message": "Failed to store contact card",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"task_id": req.TaskID,
+			"stored":  true,
+		})
+	}
+}
+
+// GetContactCard handles GET /api/v1/agents/contact-card?task_id=...
+// It returns the card the caller would share for the task (its own card or the
+// default), or the default card when no task_id is given.
+func GetContactCard(database *sql.DB, vault *core.ContactVault) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		agent, ok := getAgent(c)
+		if !ok {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "unauthorized",
+				"message": "Authentication required",
+			})
+			return
+		}
+		if !requireContactVault(c, vault) {
+			return
+		}
+
+		taskID, ok := contactCardTask(c, database, agent.ID, c.Query("task_id"))
+		if !ok {
+			return
+		}
+
+		card, err := core.LoadContactCard(database, vault, agent.ID, taskID)
+		if errors.Is(err, core.ErrContactCardNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "contact_card_not_found",
+				"message": "No contact card stored",
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"message": "Failed to read contact card",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"task_id": c.Query("task_id"),
+			"card":    card,
+		})
+	}
+}
+
+// DeleteContactCard handles DELETE /api/v1/agents/contact-card?task_id=...
+// Cards already consented to in a conversation are still exchanged.
+func DeleteContactCard(database *sql.DB, vault *core.ContactVault) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		agent, ok := getAgent(c)
+		if !ok {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "unauthorized",
+				"message": "Authentication required",
+			})
+			return
+		}
+		if !requireContactVault(c, vault) {
+			return
+		}
+
+		taskID, ok := contactCardTask(c, database, agent.ID, c.Query("task_id"))
+		if !ok {
+			return
+		}
+
+		err := core.DeleteContactCard(database, agent.ID, taskID)
+		if errors.Is(err, core.ErrContactCardNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "contact_card_not_found",
+				"message": "No contact card stored",
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"message": "Failed to delete contact card",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"task_id": c.Query("task_id"),
+			"deleted": true,
+		})
+	}
+}
+
+// GiveContactConsent handles PUT /api/v1/conversations/:id/contact-consent.
+// When both participants of a round 3 conversation have consented, each receives
+// the other's contact card as a contact_card notification.
+func GiveContactConsent(database *sql.DB, vault *core.ContactVault) gin.HandlerFunc {
+	return contactConsentHandler(database, vault, func(convID, agentID string) (*core.ContactExchangeStatus, error) {
+		return core.GiveContactConsent(database, convID, agentID)
+	})
+}
+
+// RevokeContactConsent handles DELETE /api/v1/conversations/:id/contact-consent.
+// Consent can be withdrawn until the cards have been released.
+func RevokeContactConsent(database *sql.DB, vault *core.ContactVault) gin.HandlerFunc {
+	return contactConsentHandler(database, vault, func(convID, agentID string) (*core.ContactExchangeStatus, error) {
+		return core.RevokeContactConsent(database, convID, agentID)
+	})
+}
+
+func contactConsentHandler(database *sql.DB, vault *core.ContactVault, apply func(convID, agentID string) (*core.ContactExchangeStatus, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		agent, ok := getAgent(c)
+		if !ok {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "unauthorized",
+				"message": "Authentication required",
+			})
+			return
+		}
+		if !requireContactVault(c, vault) {
+			return
+		}
+
+		convID := c.Param("id")
+
+		var initiatorAgent, targetAgent string
+		err := database.QueryRow(
+			"SELECT initiator_agent, target_agent FROM conversations WHERE id = ?",
+			convID,
+		).Scan(&initiatorAgent, &targetAgent)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "conversation_not_found",
+				"message": "Conversation not found",
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"message": "Failed to look up conversation",
+			})
+			return
+		}
+
+		if agent.ID != initiatorAgent && agent.ID != targetAgent {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "not_participant",
+				"message": "You are not a participant of this conversation",
+			})
+			return
+		}
+
+		status, err := apply(convID, agent.ID)
+		var exchangeErr *core.ContactExchangeError
+		if errors.As(err, &exchangeErr) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "contact_exchange_not_allowed",
+				"message": exchangeErr.Reason,
+			})
+			return
+		}
+		if errors.Is(err, core.ErrContactCardNotFound) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "contact_card_missing",
+				"message": "Store a contact card with PUT /agents/contact-card before consenting",
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"message": "Failed to update contact consent",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"conversation_id": convID,
+			"consented_by":    status.ConsentedBy,
+			"released":        status.Released,
+			"released_at":     status.ReleasedAt,
+		})
+	}
+}
+
+// requireContactVault responds 503 and returns false when contact exchange is disabled.
+func requireContactVault(c *gin.Context, vault *core.ContactVault) bool {
+	if vault != nil {
+		return true
+	}
+	c.JSON(http.StatusServiceUnavailable, gin.H{
+		"error":   "contact_exchange_disabled",
+		"message": "Contact exchange is not enabled on this platform",
+	})
+	return false
+}
+
+// contactCardTask resolves the caller's task_id to its internal ID, or "" for the
+// default card. Responds 404 and returns false if the task does not exist.
+func contactCardTask(c *gin.Context, database *sql.DB, agentID, taskID string) (string, bool) {
+	if taskID == "" {
+		return "", true
+	}
+	var id string
+	err := database.QueryRow("SELECT id FROM tasks WHERE task_id = ? AND agent_id = ?", taskID, agentID).Scan(&id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "task_not_found",
+			"message": "Task not found or does not belong to this agent",
+		})
+		return "", false
+	}
+	return id, true
+}
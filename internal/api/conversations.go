@@ -2,10 +2,14 @@ package api
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"agentsocial/internal/core"
+	dbpkg "agentsocial/internal/db"
 
 	"github.com/gin-gonic/gin"
 )
@@ -19,7 +23,8 @@ type CreateConversationRequest struct {
 }
 
 // CreateConversation handles POST /api/v1/conversations.
-func CreateConversation(database *sql.DB) gin.HandlerFunc {
+// The similarity of the two tasks in index is stored as the conversation's match score.
+func CreateConversation(database *sql.DB, index *core.VectorIndex) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		agent, ok := getAgent(c)
 		if !ok {
@@ -64,6 +69,23 @@ func CreateConversation(database *sql.DB) gin.HandlerFunc {
 			return
 		}
 
+		// Blocks hold in both directions.
+		blocked, err := core.AgentsBlocked(database, agent.ID, req.TargetAgentID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"message": "Failed to check blocks",
+			})
+			return
+		}
+		if blocked {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "agent_blocked",
+				"message": "You cannot contact this agent",
+			})
+			return
+		}
+
 		// Resolve task IDs — accept both internal hash ID and user-provided task_id.
 		myTaskInternalID := resolveTaskID(database, req.MyTaskID, agent.ID)
 		if myTaskInternalID == "" {
@@ -107,12 +129,31 @@ func CreateConversation(database *sql.DB) gin.HandlerFunc {
 			return
 		}
 
-		// Insert new conversation.
-		_, err = database.Exec(
-			`INSERT INTO conversations (id, initiator_agent, target_agent, initiator_task, target_task, state, created_at, updated_at)
-			 VALUES (?, ?, ?, ?, ?, 'pending_acceptance', ?, ?)`,
-			conversationID, agent.ID, req.TargetAgentID, myTaskInternalID, targetTaskInternalID, now, now,
+		// Record how well the tasks matched when the conversation was opened.
+		var matchScore sql.NullFloat64
+		if score, ok := index.Similarity(myTaskInternalID, targetTaskInternalID); ok {
+			matchScore = sql.NullFloat64{Float64: score, Valid: true}
+		}
+
+		// Insert the conversation, its history and the initial message together.
+		tx, err := database.Begin()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"message": "Failed to create conversation",
+			})
+			return
+		}
+		defer tx.Rollback()
+
+		_, err = tx.Exec(
+			`INSERT INTO conversations (id, initiator_agent, target_agent, initiator_task, target_task, state, match_score, created_at, updated_at)
+			 VALUES (?, ?, ?, ?, ?, 'pending_acceptance', ?, ?, ?)`,
+			conversationID, agent.ID, req.TargetAgentID, myTaskInternalID, targetTaskInternalID, matchScore, now, now,
 		)
+		if err == nil {
+			err = core.RecordConversationCreated(tx, conversationID, agent.ID)
+		}
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error":   "internal_error",
@@ -123,7 +164,7 @@ func CreateConversation(database *sql.DB) gin.HandlerFunc {
 
 		// Queue the initial message to the target agent.
 		msgID := core.GenerateMD5(conversationID, agent.ID, now)
-		_, err = database.Exec(
+		_, err = tx.Exec(
 			`INSERT INTO message_queue (id, conversation_id, from_agent_id, to_agent_id, content, created_at)
 			 VALUES (?, ?, ?, ?, ?, ?)`,
 			msgID, conversationID, agent.ID, req.TargetAgentID, req.InitialMessage, now,
@@ -136,6 +177,14 @@ func CreateConversation(database *sql.DB) gin.HandlerFunc {
 			return
 		}
 
+		if err := tx.Commit(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"message": "Failed to create conversation",
+			})
+			return
+		}
+
 		c.JSON(http.StatusCreated, gin.H{
 			"conversation_id": conversationID,
 			"status":          "pending_acceptance",
@@ -181,11 +230,11 @@ func ConcludeConversation(database *sql.DB) gin.HandlerFunc {
 		}
 
 		// Verify the conversation exists and the agent is a participant.
-		var initiatorAgent, targetAgent, state string
+		var initiatorAgent, targetAgent string
 		err := database.QueryRow(
-			"SELECT initiator_agent, target_agent, state FROM conversations WHERE id = ?",
+			"SELECT initiator_agent, target_agent FROM conversations WHERE id = ?",
 			convID,
-		).Scan(&initiatorAgent, &targetAgent, &state)
+		).Scan(&initiatorAgent, &targetAgent)
 
 		if err == sql.ErrNoRows {
 			c.JSON(http.StatusNotFound, gin.H{
@@ -210,17 +259,16 @@ func ConcludeConversation(database *sql.DB) gin.HandlerFunc {
 			return
 		}
 
-		// Map outcome to state.
-		newState := "concluded_no_match"
+		// Map outcome to lifecycle event.
+		event := core.EventConcludeNoMatch
 		if req.Outcome == "matched" {
-			newState = "concluded_matched"
+			event = core.EventConcludeMatched
 		}
 
-		now := time.Now().UTC().Format(time.RFC3339)
-		_, err = database.Exec(
-			"UPDATE conversations SET state = ?, updated_at = ? WHERE id = ?",
-			newState, now, convID,
-		)
+		newState, err := core.TransitionConversation(database, convID, agent.ID, event, "")
+		if writeTransitionError(c, err) {
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error":   "internal_error",
@@ -236,6 +284,207 @@ func ConcludeConversation(database *sql.DB) gin.HandlerFunc {
 	}
 }
 
+// DeclineConversationRequest is the optional body for PUT /api/v1/conversations/:id/decline.
+type DeclineConversationRequest struct {
+	Reason string `json:"reason"`
+}
+
+// maxDeclineReasonLength bounds the reason relayed to the initiator.
+const maxDeclineReasonLength = 500
+
+// AcceptConversation handles PUT /api/v1/conversations/:id/accept.
+// The target of a pending conversation request accepts it, making it active.
+func AcceptConversation(database *sql.DB) gin.HandlerFunc {
+	return respondToConversationRequest(database, true)
+}
+
+// DeclineConversation handles PUT /api/v1/conversations/:id/decline.
+// The target of a pending conversation request declines it, with an optional reason.
+func DeclineConversation(database *sql.DB) gin.HandlerFunc {
+	return respondToConversationRequest(database, false)
+}
+
+// respondToConversationRequest accepts or declines a pending_acceptance conversation
+// on behalf of its target and notifies the initiator on their next heartbeat.
+func respondToConversationRequest(database *sql.DB, accept bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		agent, ok := getAgent(c)
+		if !ok {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "unauthorized",
+				"message": "Authentication required",
+			})
+			return
+		}
+
+		convID := c.Param("id")
+
+		var req DeclineConversationRequest
+		if !accept && c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":   "invalid_request",
+					"message": "Invalid request body: " + err.Error(),
+				})
+				return
+			}
+		}
+		reason := strings.TrimSpace(req.Reason)
+		if len(reason) > maxDeclineReasonLength {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_reason",
+				"message": fmt.Sprintf("Reason must be at most %d characters", maxDeclineReasonLength),
+			})
+			return
+		}
+
+		var initiatorAgent, targetAgent string
+		err := database.QueryRow(
+			"SELECT initiator_agent, target_agent FROM conversations WHERE id = ?",
+			convID,
+		).Scan(&initiatorAgent, &targetAgent)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "conversation_not_found",
+				"message": "Conversation not found",
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"message": "Failed to look up conversation",
+			})
+			return
+		}
+
+		if agent.ID != initiatorAgent && agent.ID != targetAgent {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "not_participant",
+				"message": "You are not a participant of this conversation",
+			})
+			return
+		}
+		if agent.ID != targetAgent {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "not_target",
+				"message": "Only the agent who received the conversation request can accept or decline it",
+			})
+			return
+		}
+
+		event, notificationType, message := core.EventAccept, core.NotificationConversationAccepted, "Conversation request accepted"
+		if !accept {
+			event, notificationType, message = core.EventDecline, core.NotificationConversationDeclined, "Conversation request declined"
+		}
+
+		notice := core.ConversationNotice{AgentID: initiatorAgent, Type: notificationType, Message: message, Reason: reason}
+		newState, err := core.TransitionConversation(database, convID, agent.ID, event, reason, notice)
+		if writeTransitionError(c, err) {
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"message": "Failed to update conversation",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"conversation_id": convID,
+			"state":           newState,
+		})
+	}
+}
+
+// RoundTransitionRequest is the body for PUT /api/v1/conversations/:id/round.
+type RoundTransitionRequest struct {
+	Transition string `json:"transition" binding:"required"`
+}
+
+// TransitionConversationRound handles PUT /api/v1/conversations/:id/round.
+// Escalations (escalate_to_round2, escalate_to_round3) take effect once both
+// participants have requested them; cancel_escalation withdraws or refuses one.
+func TransitionConversationRound(database *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		agent, ok := getAgent(c)
+		if !ok {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "unauthorized",
+				"message": "Authentication required",
+			})
+			return
+		}
+
+		convID := c.Param("id")
+
+		var req RoundTransitionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_request",
+				"message": "Invalid request body: " + err.Error(),
+			})
+			return
+		}
+
+		var initiatorAgent, targetAgent string
+		err := database.QueryRow(
+			"SELECT initiator_agent, target_agent FROM conversations WHERE id = ?",
+			convID,
+		).Scan(&initiatorAgent, &targetAgent)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "conversation_not_found",
+				"message": "Conversation not found",
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"message": "Failed to look up conversation",
+			})
+			return
+		}
+
+		if agent.ID != initiatorAgent && agent.ID != targetAgent {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "not_participant",
+				"message": "You are not a participant of this conversation",
+			})
+			return
+		}
+
+		status, err := core.ApplyRoundTransition(database, convID, agent.ID, req.Transition)
+		if errors.Is(err, core.ErrUnknownTransition) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "unknown_transition",
+				"message": "Transition must be 'escalate_to_round2', 'escalate_to_round3' or 'cancel_escalation'",
+			})
+			return
+		}
+		if writeTransitionError(c, err) {
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"message": "Failed to update conversation round",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"conversation_id":         convID,
+			"round":                   status.Round,
+			"pending_transition":      status.PendingTransition,
+			"transition_requested_by": status.TransitionRequestedBy,
+			"advanced":                status.Advanced,
+		})
+	}
+}
+
 // ListConversations handles GET /api/v1/conversations.
 func ListConversations(database *sql.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -249,7 +498,8 @@ func ListConversations(database *sql.DB) gin.HandlerFunc {
 		}
 
 		rows, err := database.Query(
-			`SELECT id, initiator_agent, target_agent, initiator_task, target_task, state, created_at, updated_at
+			`SELECT id, initiator_agent, target_agent, initiator_task, target_task, state, decline_reason,
+			        round, pending_transition, transition_requested_by, created_at, updated_at
 			 FROM conversations
 			 WHERE initiator_agent = ? OR target_agent = ?
 			 ORDER BY updated_at DESC`,
@@ -265,20 +515,25 @@ func ListConversations(database *sql.DB) gin.HandlerFunc {
 		defer rows.Close()
 
 		type ConversationResponse struct {
-			ID             string `json:"id"`
-			InitiatorAgent string `json:"initiator_agent"`
-			TargetAgent    string `json:"target_agent"`
-			InitiatorTask  string `json:"initiator_task"`
-			TargetTask     string `json:"target_task"`
-			State          string `json:"state"`
-			CreatedAt      string `json:"created_at"`
-			UpdatedAt      string `json:"updated_at"`
+			ID                    string `json:"id"`
+			InitiatorAgent        string `json:"initiator_agent"`
+			TargetAgent           string `json:"target_agent"`
+			InitiatorTask         string `json:"initiator_task"`
+			TargetTask            string `json:"target_task"`
+			State                 string `json:"state"`
+			DeclineReason         string `json:"decline_reason,omitempty"`
+			Round                 int    `json:"round"`
+			PendingTransition     string `json:"pending_transition,omitempty"`
+			TransitionRequestedBy string `json:"transition_requested_by,omitempty"`
+			CreatedAt             string `json:"created_at"`
+			UpdatedAt             string `json:"updated_at"`
 		}
 
 		var conversations []ConversationResponse
 		for rows.Next() {
 			var conv ConversationResponse
-			if err := rows.Scan(&conv.ID, &conv.InitiatorAgent, &conv.TargetAgent, &conv.InitiatorTask, &conv.TargetTask, &conv.State, &conv.CreatedAt, &conv.UpdatedAt); err != nil {
+			if err := rows.Scan(&conv.ID, &conv.InitiatorAgent, &conv.TargetAgent, &conv.InitiatorTask, &conv.TargetTask, &conv.State, &conv.DeclineReason,
+				&conv.Round, &conv.PendingTransition, &conv.TransitionRequestedBy, &conv.CreatedAt, &conv.UpdatedAt); err != nil {
 				continue
 			}
 			conversations = append(conversations, conv)
@@ -294,6 +549,224 @@ func ListConversations(database *sql.DB) gin.HandlerFunc {
 	}
 }
 
+// conversationTask is a task's public info as shown in conversation details.
+type conversationTask struct {
+	ID        string `json:"id"`
+	Mode      string `json:"mode"`
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    string `json:"status"`
+	CreatedAt string `json:"created_at"`
+}
+
+// GetConversation handles GET /api/v1/conversations/:id.
+// Returns the conversation with both tasks' public info, the counterpart's public
+// profile, the lifecycle history and the number of messages still queued each way.
+func GetConversation(database *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		agent, ok := getAgent(c)
+		if !ok {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "unauthorized",
+				"message": "Authentication required",
+			})
+			return
+		}
+
+		convID := c.Param("id")
+
+		var initiatorAgent, targetAgent, initiatorTask, targetTask, state, declineReason string
+		var pendingTransition, transitionRequestedBy, createdAt, updatedAt string
+		var round int
+		var contactsReleasedAt sql.NullString
+		var matchScore sql.NullFloat64
+		err := database.QueryRow(
+			`SELECT initiator_agent, target_agent, initiator_task, target_task, state, decline_reason,
+			        round, pending_transition, transition_requested_by, contacts_released_at, match_score, created_at, updated_at
+			 FROM conversations WHERE id = ?`,
+			convID,
+		).Scan(&initiatorAgent, &targetAgent, &initiatorTask, &targetTask, &state, &declineReason,
+			&round, &pendingTransition, &transitionRequestedBy, &contactsReleasedAt, &matchScore, &createdAt, &updatedAt)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "conversation_not_found",
+				"message": "Conversation not found",
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"message": "Failed to look up conversation",
+			})
+			return
+		}
+
+		if agent.ID != initiatorAgent && agent.ID != targetAgent {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "not_participant",
+				"message": "You are not a participant of this conversation",
+			})
+			return
+		}
+
+		role, counterpartID, myTaskID, theirTaskID := "initiator", targetAgent, initiatorTask, targetTask
+		if agent.ID == targetAgent {
+			role, counterpartID, myTaskID, theirTaskID = "target", initiatorAgent, targetTask, initiatorTask
+		}
+
+		myTask, err := lookupConversationTask(database, myTaskID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"message": "Failed to look up task",
+			})
+			return
+		}
+		theirTask, err := lookupConversationTask(database, theirTaskID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"message": "Failed to look up task",
+			})
+			return
+		}
+
+		// The counterpart's profile is shown even if they are no longer active.
+		var displayName, publicBio, agentCreatedAt string
+		var lastHeartbeat sql.NullString
+		err = database.QueryRow(
+			"SELECT display_name, public_bio, last_heartbeat, created_at FROM agents WHERE id = ?",
+			counterpartID,
+		).Scan(&displayName, &publicBio, &lastHeartbeat, &agentCreatedAt)
+		if err != nil && err != sql.ErrNoRows {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"message": "Failed to look up counterpart",
+			})
+			return
+		}
+
+		history, err := conversationHistory(database, convID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"message": "Failed to fetch conversation history",
+			})
+			return
+		}
+
+		// Queued messages are deleted once the recipient pulls them.
+		var pendingToMe, pendingToThem int
+		err = database.QueryRow(
+			`SELECT COALESCE(SUM(to_agent_id = ?), 0), COALESCE(SUM(to_agent_id = ?), 0)
+			 FROM message_queue WHERE conversation_id = ?`,
+			agent.ID, counterpartID, convID,
+		).Scan(&pendingToMe, &pendingToThem)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"message": "Failed to count pending messages",
+			})
+			return
+		}
+
+		var score interface{}
+		if matchScore.Valid {
+			score = matchScore.Float64
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"conversation": gin.H{
+				"id":                      convID,
+				"role":                    role,
+				"state":                   state,
+				"decline_reason":          declineReason,
+				"round":                   round,
+				"pending_transition":      pendingTransition,
+				"transition_requested_by": transitionRequestedBy,
+				"contacts_released_at":    contactsReleasedAt.String,
+				"match_score":             score,
+				"created_at":              createdAt,
+				"updated_at":              updatedAt,
+			},
+			"my_task":    myTask,
+			"their_task": theirTask,
+			"counterpart": gin.H{
+				"id":             counterpartID,
+				"display_name":   displayName,
+				"public_bio":     publicBio,
+				"last_heartbeat": lastHeartbeat.String,
+				"created_at":     agentCreatedAt,
+			},
+			"history": history,
+			"pending_messages": gin.H{
+				"to_me":   pendingToMe,
+				"to_them": pendingToThem,
+			},
+		})
+	}
+}
+
+// lookupConversationTask returns a task's public info, or nil if it no longer exists.
+func lookupConversationTask(database *sql.DB, taskID string) (*conversationTask, error) {
+	t := conversationTask{ID: taskID}
+	err := database.QueryRow(
+		"SELECT mode, type, title, status, created_at FROM tasks WHERE id = ?",
+		taskID,
+	).Scan(&t.Mode, &t.Type, &t.Title, &t.Status, &t.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// conversationHistory returns a conversation's lifecycle and round transitions,
+// oldest first.
+func conversationHistory(database *sql.DB, convID string) ([]dbpkg.ConversationTransition, error) {
+	rows, err := database.Query(
+		`SELECT id, conversation_id, event, from_state, to_state, from_round, to_round, actor, reason, created_at
+		 FROM conversation_transitions
+		 WHERE conversation_id = ?
+		 ORDER BY created_at, rowid`,
+		convID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []dbpkg.ConversationTransition{}
+	for rows.Next() {
+		var t dbpkg.ConversationTransition
+		if err := rows.Scan(&t.ID, &t.ConversationID, &t.Event, &t.FromState, &t.ToState,
+			&t.FromRound, &t.ToRound, &t.Actor, &t.Reason, &t.CreatedAt); err != nil {
+			continue
+		}
+		history = append(history, t)
+	}
+	return history, rows.Err()
+}
+
+// writeTransitionError responds 409 invalid_transition and returns true if err is a
+// *core.TransitionError.
+func writeTransitionError(c *gin.Context, err error) bool {
+	var transitionErr *core.TransitionError
+	if !errors.As(err, &transitionErr) {
+		return false
+	}
+	c.JSON(http.StatusConflict, gin.H{
+		"error":   "invalid_transition",
+		"message": transitionErr.Error(),
+		"state":   transitionErr.State,
+		"round":   transitionErr.Round,
+	})
+	return true
+}
+
 // resolveTaskID tries to find a task's internal ID. Accepts either the internal
 // hash ID (PK) or the user-provided task_id. Returns empty string if not found.
 func resolveTaskID(database *sql.DB, taskID string, agentID string) string {
@@ -0,0 +1,156 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// registerPair registers a hiring beacon agent "a" and a job-seeking radar agent "b".
+func registerPair(s *testServer) (aID, aToken, bID, bToken string) {
+	s.t.Helper()
+	aID, aToken = s.register("a", TaskRequest{TaskID: "t1", Mode: "beacon", Type: "hiring", Title: "Go dev", Keywords: []string{"golang"}})
+	bID, bToken = s.register("b", TaskRequest{TaskID: "r1", Mode: "radar", Type: "job-seeking", Title: "Looking", Keywords: []string{"golang"}})
+	return aID, aToken, bID, bToken
+}
+
+// openConversation has b open a conversation from r1 to a's t1.
+func openConversation(s *testServer, aID, bToken string) (int, map[string]interface{}) {
+	s.t.Helper()
+	return s.do(http.MethodPost, "/api/v1/conversations", bToken, CreateConversationRequest{
+		TargetAgentID: aID, MyTaskID: "r1", TargetTaskID: "t1", InitialMessage: "hi",
+	})
+}
+
+func TestCreateConversationHonoursBlocks(t *testing.T) {
+	tests := []struct {
+		name     string
+		blocker  string // "a", "b" or "" for no block
+		wantCode int
+	}{
+		{"no block", "", http.StatusCreated},
+		{"target blocked the initiator", "a", http.StatusForbidden},
+		{"initiator blocked the target", "b", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			aID, aToken, bID, bToken := registerPair(s)
+			switch tt.blocker {
+			case "a":
+				s.do(http.MethodPost, "/api/v1/agents/blocks", aToken, gin.H{"agent_id": bID})
+			case "b":
+				s.do(http.MethodPost, "/api/v1/agents/blocks", bToken, gin.H{"agent_id": aID})
+			}
+
+			code, resp := openConversation(s, aID, bToken)
+			if code != tt.wantCode {
+				t.Fatalf("create: %d %v, want %d", code, resp, tt.wantCode)
+			}
+			var conversations, messages int
+			_ = s.db.QueryRow("SELECT COUNT(*) FROM conversations").Scan(&conversations)
+			_ = s.db.QueryRow("SELECT COUNT(*) FROM message_queue").Scan(&messages)
+			if created := tt.wantCode == http.StatusCreated; (conversations == 1) != created || (messages == 1) != created {
+				t.Errorf("conversations, messages = %d, %d after a %d", conversations, messages, code)
+			}
+		})
+	}
+}
+
+func TestRespondToConversationRequestNotifies(t *testing.T) {
+	tests := []struct {
+		action, wantType, wantState string
+	}{
+		{"accept", "conversation_accepted", "active"},
+		{"decline", "conversation_declined", "declined"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			s := newTestServer(t)
+			aID, aToken, bID, bToken := registerPair(s)
+			_, resp := openConversation(s, aID, bToken)
+			convID := resp["conversation_id"].(string)
+
+			code, resp := s.do(http.MethodPut, "/api/v1/conversations/"+convID+"/"+tt.action, aToken, nil)
+			if code != http.StatusOK || resp["state"] != tt.wantState {
+				t.Fatalf("%s: %d %v", tt.action, code, resp)
+			}
+			// Answering again is rejected and must not notify twice.
+			if code, _ := s.do(http.MethodPut, "/api/v1/conversations/"+convID+"/"+tt.action, aToken, nil); code != http.StatusConflict {
+				t.Errorf("second %s: %d, want 409", tt.action, code)
+			}
+
+			var count int
+			err := s.db.QueryRow(
+				"SELECT COUNT(*) FROM conversation_notifications WHERE conversation_id = ? AND agent_id = ? AND from_agent_id = ? AND type = ?",
+				convID, bID, aID, tt.wantType,
+			).Scan(&count)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != 1 {
+				t.Errorf("%d %s notifications for the initiator, want 1", count, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestGetConversation(t *testing.T) {
+	s := newTestServer(t)
+	aID, aToken, bID, bToken := registerPair(s)
+	_, outsiderToken := s.register("c", TaskRequest{TaskID: "r2", Mode: "radar", Type: "job-seeking", Title: "Looking", Keywords: []string{"golang"}})
+	_, resp := openConversation(s, aID, bToken)
+	convID := resp["conversation_id"].(string)
+
+	tests := []struct {
+		name        string
+		token, id   string
+		wantCode    int
+		wantError   string
+		wantRole    string
+		counterpart string
+		myTaskTitle string
+		wantToMe    float64
+		wantToThem  float64
+	}{
+		{"initiator", bToken, convID, http.StatusOK, "", "initiator", aID, "Looking", 0, 1},
+		{"target", aToken, convID, http.StatusOK, "", "target", bID, "Go dev", 1, 0},
+		{"non-participant", outsiderToken, convID, http.StatusForbidden, "not_participant", "", "", "", 0, 0},
+		{"missing", aToken, "no-such-conversation", http.StatusNotFound, "conversation_not_found", "", "", "", 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := s.do(http.MethodGet, "/api/v1/conversations/"+tt.id, tt.token, nil)
+			if code != tt.wantCode {
+				t.Fatalf("%d %v, want %d", code, resp, tt.wantCode)
+			}
+			if tt.wantError != "" {
+				if resp["error"] != tt.wantError {
+					t.Errorf("error = %v, want %s", resp["error"], tt.wantError)
+				}
+				if _, leaked := resp["conversation"]; leaked {
+					t.Error("conversation returned with an error")
+				}
+				return
+			}
+
+			conv := resp["conversation"].(map[string]interface{})
+			counterpart := resp["counterpart"].(map[string]interface{})
+			myTask := resp["my_task"].(map[string]interface{})
+			pending := resp["pending_messages"].(map[string]interface{})
+			if conv["id"] != convID || conv["role"] != tt.wantRole || conv["state"] != "pending_acceptance" {
+				t.Errorf("conversation = %v, want %s as %s, pending_acceptance", conv, convID, tt.wantRole)
+			}
+			if counterpart["id"] != tt.counterpart || myTask["title"] != tt.myTaskTitle {
+				t.Errorf("counterpart %v, my task %v; want %s, %q", counterpart["id"], myTask["title"], tt.counterpart, tt.myTaskTitle)
+			}
+			if pending["to_me"] != tt.wantToMe || pending["to_them"] != tt.wantToThem {
+				t.Errorf("pending_messages = %v, want to_me %v, to_them %v", pending, tt.wantToMe, tt.wantToThem)
+			}
+			if history := resp["history"].([]interface{}); len(history) != 1 {
+				t.Errorf("history = %v, want the create event", history)
+			}
+		})
+	}
+}
@@ -2,6 +2,8 @@ package api
 
 import (
 	"database/sql"
+	"errors"
+	"log"
 	"net/http"
 	"time"
 
@@ -21,6 +23,14 @@ type HeartbeatRequest struct {
 	Outbound []OutboundMessage `json:"outbound"`
 }
 
+// RejectedMessage reports an outbound message that was not sent.
+type RejectedMessage struct {
+	ConversationID string `json:"conversation_id"`
+	Error          string `json:"error"`
+	State          string `json:"state,omitempty"`
+	Message        string `json:"message"`
+}
+
 // InboundMessage represents a message received during a heartbeat pull.
 type InboundMessage struct {
 	ID             string `json:"id"`
@@ -31,17 +41,27 @@ type InboundMessage struct {
 }
 
 // Notification represents a notification delivered during heartbeat.
+// For new_match notifications, TaskID is the caller's standing radar task and
+// MatchTaskID the matching beacon, which belongs to FromAgentID. Reason is set on
+// conversation_declined notifications when the decliner gave one; ContactCard
+// carries FromAgentID's card on contact_card notifications.
 type Notification struct {
-	Type           string `json:"type"`
-	ConversationID string `json:"conversation_id,omitempty"`
-	FromAgentID    string `json:"from_agent_id,omitempty"`
-	Message        string `json:"message"`
-	CreatedAt      string `json:"created_at"`
+	Type           string            `json:"type"`
+	ConversationID string            `json:"conversation_id,omitempty"`
+	FromAgentID    string            `json:"from_agent_id,omitempty"`
+	TaskID         string            `json:"task_id,omitempty"`
+	MatchTaskID    string            `json:"match_task_id,omitempty"`
+	Score          float64           `json:"score,omitempty"`
+	Message        string            `json:"message"`
+	Reason         string            `json:"reason,omitempty"`
+	ContactCard    map[string]string `json:"contact_card,omitempty"`
+	CreatedAt      string            `json:"created_at"`
 }
 
 // Heartbeat handles POST /api/v1/heartbeat.
 // It sends outbound messages, pulls inbound messages, and deletes them (relay only).
-func Heartbeat(database *sql.DB) gin.HandlerFunc {
+// vault decrypts released contact cards; it is nil when contact exchange is disabled.
+func Heartbeat(database *sql.DB, vault *core.ContactVault) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		agent, ok := getAgent(c)
 		if !ok {
@@ -61,6 +81,7 @@ func Heartbeat(database *sql.DB) gin.HandlerFunc {
 		now := time.Now().UTC().Format(time.RFC3339)
 
 		// Process outbound messages.
+		var rejected []RejectedMessage
 		for _, out := range req.Outbound {
 			// Look up the conversation to find the other agent.
 			var initiatorAgent, targetAgent, convState string
@@ -82,12 +103,32 @@ func Heartbeat(database *sql.DB) gin.HandlerFunc {
 				continue
 			}
 
-			// Auto-accept: if target replies, move conversation to active.
-			if convState == "pending_acceptance" && agent.ID == targetAgent {
-				_, _ = database.Exec(
-					"UPDATE conversations SET state = 'active', updated_at = ? WHERE id = ?",
-					now, out.ConversationID,
-				)
+			// Declined, concluded and expired conversations are closed.
+			if !core.ConversationAcceptsMessages(convState) {
+				rejected = append(rejected, RejectedMessage{
+					ConversationID: out.ConversationID,
+					Error:          "invalid_transition",
+					State:          convState,
+					Message:        "Conversation is " + convState + " and no longer accepts messages",
+				})
+				continue
+			}
+
+			// Auto-accept: if target replies, move conversation to active. Losing a
+			// race with expiry or a decline leaves the state alone; drop the message.
+			if convState == core.StatePendingAcceptance && agent.ID == targetAgent {
+				if _, err := core.TransitionConversation(database, out.ConversationID, agent.ID, core.EventAccept, ""); err != nil {
+					var transitionErr *core.TransitionError
+					if errors.As(err, &transitionErr) && transitionErr.State != core.StateActive {
+						rejected = append(rejected, RejectedMessage{
+							ConversationID: out.ConversationID,
+							Error:          "invalid_transition",
+							State:          transitionErr.State,
+							Message:        "Conversation is " + transitionErr.State + " and no longer accepts messages",
+						})
+						continue
+					}
+				}
 			}
 
 			// Insert into message queue.
@@ -169,9 +210,92 @@ func Heartbeat(database *sql.DB) gin.HandlerFunc {
 			}
 		}
 
+		// Pull answers to this agent's conversation requests and other changes made
+		// by the other side. Each is delivered once.
+		convNotifRows, err := database.Query(
+			`SELECT id, conversation_id, from_agent_id, type, message, reason, payload, created_at
+			 FROM conversation_notifications
+			 WHERE agent_id = ? AND delivered_at IS NULL
+			 ORDER BY created_at ASC`,
+			agent.ID,
+		)
+		if err != nil {
+			// Non-fatal: skip conversation notifications this round.
+			convNotifRows = nil
+		}
+
+		var convNotifIDs []string
+		if convNotifRows != nil {
+			defer convNotifRows.Close()
+			for convNotifRows.Next() {
+				var id string
+				var n Notification
+				var payload []byte
+				if err := convNotifRows.Scan(&id, &n.ConversationID, &n.FromAgentID, &n.Type, &n.Message, &n.Reason, &payload, &n.CreatedAt); err != nil {
+					continue
+				}
+				if payload != nil {
+					// Held back until it can be decrypted.
+					if vault == nil {
+						continue
+					}
+					card, err := vault.Open(payload)
+					if err != nil {
+						log.Printf("Heartbeat: %v (notification %s)", err, id)
+						continue
+					}
+					n.ContactCard = card
+				}
+				notifications = append(notifications, n)
+				convNotifIDs = append(convNotifIDs, id)
+			}
+			convNotifRows.Close()
+		}
+		for _, id := range convNotifIDs {
+			_, _ = database.Exec("UPDATE conversation_notifications SET delivered_at = ?, payload = NULL WHERE id = ?", now, id)
+		}
+
+		// Pull new matches for standing searches. Each is delivered once; matches
+		// whose beacon is no longer active are held back.
+		matchRows, err := database.Query(
+			`SELECT n.id, n.match_agent_id, n.match_task_id, n.score, n.created_at, t.task_id
+			 FROM match_notifications n
+			 JOIN tasks t ON t.id = n.task_id
+			 JOIN tasks m ON m.id = n.match_task_id
+			 WHERE n.agent_id = ? AND n.delivered_at IS NULL
+			   AND m.status = 'active'
+			 ORDER BY n.score DESC`,
+			agent.ID,
+		)
+		if err != nil {
+			// Non-fatal: skip match notifications this round.
+			matchRows = nil
+		}
+
+		var matchIDs []string
+		if matchRows != nil {
+			defer matchRows.Close()
+			for matchRows.Next() {
+				var id string
+				n := Notification{Type: "new_match", Message: "New match for your standing search"}
+				if err := matchRows.Scan(&id, &n.FromAgentID, &n.MatchTaskID, &n.Score, &n.CreatedAt, &n.TaskID); err != nil {
+					continue
+				}
+				notifications = append(notifications, n)
+				matchIDs = append(matchIDs, id)
+			}
+			matchRows.Close()
+		}
+		for _, id := range matchIDs {
+			_, _ = database.Exec("UPDATE match_notifications SET delivered_at = ? WHERE id = ?", now, id)
+		}
+
 		if inbound == nil {
 			inbound = []InboundMessage{}
 		}
+		if rejected == nil {
+			rejected = []RejectedMessage{}
+		}
 		if notifications == nil {
 			notifications = []Notification{}
 		}
@@ -179,6 +303,7 @@ func Heartbeat(database *sql.DB) gin.HandlerFunc {
 		c.JSON(http.StatusOK, gin.H{
 			"inbound":       inbound,
 			"notifications": notifications,
+			"rejected":      rejected,
 		})
 	}
 }
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"agentsocial/internal/config"
+	"agentsocial/internal/core"
+	dbpkg "agentsocial/internal/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testServer is the API over a fresh database, with the offline local embedder.
+type testServer struct {
+	t      *testing.T
+	db     *sql.DB
+	cfg    *config.Config
+	index  *core.VectorIndex
+	router *gin.Engine
+}
+
+// newTestServer starts the API on a migrated database in a temporary directory.
+// env overrides configuration as KEY, value pairs.
+func newTestServer(t *testing.T, env ...string) *testServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard
+	t.Setenv("EMBEDDING_PROVIDER", "local")
+	t.Setenv("REGISTRATION_DAILY_LIMIT", "100")
+	for i := 0; i+1 < len(env); i += 2 {
+		t.Setenv(env[i], env[i+1])
+	}
+	cfg := config.Load()
+
+	database, err := dbpkg.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	embClient := core.NewEmbedder(cfg)
+	index := core.NewVectorIndex(embClient.Model(), embClient.Dimensions())
+	return &testServer{
+		t:      t,
+		db:     database,
+		cfg:    cfg,
+		index:  index,
+		router: SetupRouter(database, cfg, embClient, index, nil, nil),
+	}
+}
+
+// do sends a JSON request, authenticated if token is set, and decodes the JSON
+// response into a map.
+func (s *testServer) do(method, path, token string, body interface{}) (int, map[string]interface{}) {
+	s.t.Helper()
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			s.t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &payload)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		s.t.Fatalf("%s %s: response is not JSON: %s", method, path, w.Body.String())
+	}
+	return w.Code, resp
+}
+
+// register creates an agent with the given tasks and returns its ID and token.
+func (s *testServer) register(name string, tasks ...TaskRequest) (agentID, token string) {
+	s.t.Helper()
+	code, resp := s.do(http.MethodPost, "/api/v1/agents/register", "", RegisterRequest{
+		DisplayName: name,
+		IPAddress:   "ip-" + name,
+		MACAddress:  "mac-" + name,
+		Tasks:       tasks,
+	})
+	if code != http.StatusCreated {
+		s.t.Fatalf("register %s: %d %v", name, code, resp)
+	}
+	return resp["agent_id"].(string), resp["agent_token"].(string)
+}
+
+// taskID returns the platform ID of an agent's task.
+func (s *testServer) taskID(agentID, taskID string) string {
+	s.t.Helper()
+	var id string
+	if err := s.db.QueryRow("SELECT id FROM tasks WHERE agent_id = ? AND task_id = ?", agentID, taskID).Scan(&id); err != nil {
+		s.t.Fatalf("task %s of %s: %v", taskID, agentID, err)
+	}
+	return id
+}
@@ -7,6 +7,8 @@ import (
 	"strconv"
 	"time"
 
+	"agentsocial/internal/core"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -282,16 +284,35 @@ func GetPublicStats(database *sql.DB) gin.HandlerFunc {
 		).Scan(&totalMatches)
 
 		c.JSON(http.StatusOK, gin.H{
-			"total_agents":       totalAgents,
-			"active_agents_24h":  activeAgents24h,
-			"total_tasks":        totalTasks,
+			"total_agents":      totalAgents,
+			"active_agents_24h": activeAgents24h,
+			"total_tasks":       totalTasks,
 			"tasks_by_mode": gin.H{
 				"beacon": beaconTasks,
 				"radar":  radarTasks,
 			},
-			"tasks_by_type":      tasksByType,
+			"tasks_by_type":       tasksByType,
 			"total_conversations": totalConversations,
-			"total_matches":      totalMatches,
+			"total_matches":       totalMatches,
+		})
+	}
+}
+
+// GetHealth handles GET /api/v1/public/health.
+// Reports "degraded" while the embedding provider's circuit breaker is not closed.
+// A deployment without a provider is "ok"; its embedding mode is "keyword_only".
+func GetHealth(embClient core.Embedder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		embStatus := embClient.Status()
+
+		status := "ok"
+		if embStatus.Degraded {
+			status = "degraded"
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"status":    status,
+			"embedding": embStatus,
 		})
 	}
 }
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestHealthAndDiscoverability(t *testing.T) {
+	tests := []struct {
+		provider     string
+		wantMode     string
+		wantTask     string
+		discoverable bool
+	}{
+		{"local", "semantic", "ready", true},
+		{"none", "keyword_only", "keyword_only", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			s := newTestServer(t, "EMBEDDING_PROVIDER", tt.provider)
+
+			code, resp := s.do(http.MethodGet, "/api/v1/public/health", "", nil)
+			if code != http.StatusOK || resp["status"] != "ok" {
+				t.Fatalf("health = %d %v, want 200 ok", code, resp)
+			}
+			embedding := resp["embedding"].(map[string]interface{})
+			if embedding["mode"] != tt.wantMode || embedding["degraded"] != false {
+				t.Errorf("embedding = %v, want mode %s, not degraded", embedding, tt.wantMode)
+			}
+
+			_, token := s.register("company", TaskRequest{
+				TaskID: "t1", Mode: "beacon", Type: "hiring", Title: "Engineer", Keywords: []string{"go"},
+			})
+			code, resp = s.do(http.MethodGet, "/api/v1/agents/me", token, nil)
+			if code != http.StatusOK {
+				t.Fatalf("me = %d %v", code, resp)
+			}
+			task := resp["tasks"].([]interface{})[0].(map[string]interface{})
+			status := task["embedding"].(map[string]interface{})
+			if status["status"] != tt.wantTask || status["discoverable"] != tt.discoverable {
+				t.Errorf("task embedding = %v, want %s, discoverable %v", status, tt.wantTask, tt.discoverable)
+			}
+		})
+	}
+}
@@ -14,7 +14,8 @@ import (
 )
 
 // SetupRouter creates and configures the gin router with all routes and middleware.
-func SetupRouter(db *sql.DB, cfg *config.Config, embClient *core.EmbeddingClient) *gin.Engine {
+// vault is nil when contact exchange is disabled.
+func SetupRouter(db *sql.DB, cfg *config.Config, embClient core.Embedder, index *core.VectorIndex, priors *core.OutcomePriors, vault *core.ContactVault) *gin.Engine {
 	router := gin.Default()
 
 	// CORS middleware: allow all origins for development.
@@ -30,7 +31,7 @@ func SetupRouter(db *sql.DB, cfg *config.Config, embClient *core.EmbeddingClient
 	v1 := router.Group("/api/v1")
 	{
 		// Public routes (no authentication required).
-		v1.POST("/agents/register", RegisterAgent(db, cfg, embClient))
+		v1.POST("/agents/register", RegisterAgent(db, cfg, embClient, index))
 
 		pub := v1.Group("/public")
 		{
@@ -38,20 +39,33 @@ func SetupRouter(db *sql.DB, cfg *config.Config, embClient *core.EmbeddingClient
 			pub.GET("/agents/:id", GetPublicAgent(db))
 			pub.GET("/tasks/:id", GetPublicTask(db))
 			pub.GET("/stats", GetPublicStats(db))
+			pub.GET("/health", GetHealth(embClient))
 		}
 
 		// Authenticated routes.
 		auth := v1.Group("")
 		auth.Use(AuthMiddleware(db))
 		{
-			auth.GET("/agents/me", GetMe(db))
-			auth.POST("/agents/tasks", CreateTask(db, embClient))
-			auth.PUT("/agents/tasks/:taskId", UpdateTask(db, embClient))
-			auth.POST("/scan", Scan(db, cfg, embClient))
-			auth.POST("/conversations", CreateConversation(db))
+			auth.GET("/agents/me", GetMe(db, embClient))
+			auth.POST("/agents/tasks", CreateTask(db, cfg, embClient, index))
+			auth.PUT("/agents/tasks/:taskId", UpdateTask(db, cfg, embClient, index))
+			auth.GET("/agents/contact-card", GetContactCard(db, vault))
+			auth.PUT("/agents/contact-card", PutContactCard(db, vault))
+			auth.DELETE("/agents/contact-card", DeleteContactCard(db, vault))
+			auth.GET("/agents/blocks", ListBlocks(db))
+			auth.POST("/agents/blocks", CreateBlock(db))
+			auth.DELETE("/agents/blocks/:agentId", DeleteBlock(db))
+			auth.POST("/scan", Scan(db, cfg, embClient, index, priors))
+			auth.POST("/conversations", CreateConversation(db, index))
 			auth.GET("/conversations", ListConversations(db))
+			auth.GET("/conversations/:id", GetConversation(db))
+			auth.PUT("/conversations/:id/accept", AcceptConversation(db))
+			auth.PUT("/conversations/:id/decline", DeclineConversation(db))
+			auth.PUT("/conversations/:id/round", TransitionConversationRound(db))
+			auth.PUT("/conversations/:id/contact-consent", GiveContactConsent(db, vault))
+			auth.DELETE("/conversations/:id/contact-consent", RevokeContactConsent(db, vault))
 			auth.PUT("/conversations/:id/conclude", ConcludeConversation(db))
-			auth.POST("/heartbeat", Heartbeat(db))
+			auth.POST("/heartbeat", Heartbeat(db, vault))
 			auth.POST("/reports", CreateReport(db, cfg))
 		}
 	}
@@ -2,6 +2,9 @@ package api
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"strings"
 	"time"
@@ -14,14 +17,26 @@ import (
 
 // ScanRequest is the body for POST /api/v1/scan.
 type ScanRequest struct {
-	TaskID   string   `json:"task_id" binding:"required"`
-	Keywords []string `json:"keywords" binding:"required"`
+	TaskID string `json:"task_id" binding:"required"`
+	// Keywords are optional: without them the scan uses the task's stored embedding
+	// and keywords.
+	Keywords []string `json:"keywords"`
+	// KeywordMode is how Keywords combine with the task's stored embedding:
+	// "replace" (default) searches by the keywords alone, "blend" averages the two.
+	KeywordMode string `json:"keyword_mode"`
+	// IncludeContacted keeps counterparts this task already has a conversation with,
+	// annotated with the conversation, instead of dropping them.
+	IncludeContacted bool `json:"include_contacted"`
+	// Filters are hard constraints on the counterpart task's attributes, e.g.
+	// {"remote": true, "salary": {"min": 30000}}. Tasks missing an attribute are excluded.
+	Filters map[string]interface{} `json:"filters"`
 }
 
 // Scan handles POST /api/v1/scan.
 // It finds matching tasks based on keyword embeddings.
 // Beacon tasks are returned to Radar agents, and vice versa.
-func Scan(database *sql.DB, cfg *config.Config, embClient *core.EmbeddingClient) gin.HandlerFunc {
+// priors is nil unless outcome re-ranking is enabled.
+func Scan(database *sql.DB, cfg *config.Config, embClient core.Embedder, index *core.VectorIndex, priors *core.OutcomePriors) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		agent, ok := getAgent(c)
 		if !ok {
@@ -41,12 +56,32 @@ func Scan(database *sql.DB, cfg *config.Config, embClient *core.EmbeddingClient)
 			return
 		}
 
-		// Look up the agent's task to determine its mode.
-		var taskMode string
+		if err := core.ValidateAttributeFilters(req.Filters); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_filters",
+				"message": err.Error(),
+			})
+			return
+		}
+
+		keywordMode := req.KeywordMode
+		if keywordMode == "" {
+			keywordMode = "replace"
+		}
+		if keywordMode != "replace" && keywordMode != "blend" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_keyword_mode",
+				"message": "keyword_mode must be 'replace' or 'blend'",
+			})
+			return
+		}
+
+		// Look up the agent's task to determine its mode, type and stored keywords.
+		var taskInternalID, taskMode, taskType, storedKeywordsJSON string
 		err := database.QueryRow(
-			"SELECT mode FROM tasks WHERE task_id = ? AND agent_id = ?",
+			"SELECT id, mode, type, keywords FROM tasks WHERE task_id = ? AND agent_id = ?",
 			req.TaskID, agent.ID,
-		).Scan(&taskMode)
+		).Scan(&taskInternalID, &taskMode, &taskType, &storedKeywordsJSON)
 		if err == sql.ErrNoRows {
 			c.JSON(http.StatusNotFound, gin.H{
 				"error":   "task_not_found",
@@ -62,25 +97,126 @@ func Scan(database *sql.DB, cfg *config.Config, embClient *core.EmbeddingClient)
 			return
 		}
 
-		// Compute embedding from keywords.
-		keywordsText := strings.Join(req.Keywords, " ")
-		queryEmbedding, err := embClient.GetEmbedding(keywordsText)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error":   "embedding_error",
-				"message": "Failed to compute embedding: " + err.Error(),
+		// Build the query. Without keywords, search by the task's stored embedding
+		// (embedding its stored keywords if the vector is missing). With keywords,
+		// embed them and optionally blend with the stored vector.
+		var storedKeywords []string
+		_ = json.Unmarshal([]byte(storedKeywordsJSON), &storedKeywords)
+		storedEmbedding, hasStored := index.Vector(taskInternalID)
+
+		keywords, querySource := req.Keywords, "keywords"
+		var queryEmbedding []float32
+		if len(req.Keywords) == 0 {
+			keywords, querySource = storedKeywords, "stored"
+			if hasStored {
+				queryEmbedding = storedEmbedding
+			}
+		} else if keywordMode == "blend" {
+			keywords, querySource = mergeKeywords(req.Keywords, storedKeywords), "blended"
+		}
+		if len(keywords) == 0 && queryEmbedding == nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "keywords_required",
+				"message": "The task has no stored keywords; provide keywords to scan with",
 			})
 			return
 		}
 
+		searchMode := "hybrid"
+		if cfg.LexicalWeight <= 0 {
+			searchMode = "semantic"
+		}
+		if queryEmbedding == nil {
+			// Blending with a stored vector embeds the new keywords alone; without
+			// one, the merged keyword list stands in for it.
+			blendVectors := querySource == "blended" && hasStored
+			embedText := strings.Join(keywords, " ")
+			if blendVectors {
+				embedText = strings.Join(req.Keywords, " ")
+			}
+			queryEmbedding, err = embClient.GetEmbedding(c.Request.Context(), embedText)
+			if errors.Is(err, core.ErrEmbeddingNotConfigured) {
+				// No embedding provider: fall back to keyword-only search.
+				queryEmbedding, err, searchMode = nil, nil, "lexical"
+			}
+			if errors.Is(err, core.ErrEmbeddingUnavailable) {
+				c.JSON(http.StatusServiceUnavailable, gin.H{
+					"error":   "embedding_unavailable",
+					"message": "Embedding provider is temporarily unavailable. Retry later.",
+					"status":  embClient.Status(),
+				})
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error":   "embedding_error",
+					"message": "Failed to compute embedding: " + err.Error(),
+				})
+				return
+			}
+			if blendVectors && queryEmbedding != nil {
+				queryEmbedding = core.BlendEmbeddings(queryEmbedding, storedEmbedding)
+			}
+		}
+
 		// Compute heartbeat cutoff to exclude inactive agents.
 		heartbeatCutoff := ""
 		if cfg.AgentInactiveDays > 0 {
 			heartbeatCutoff = time.Now().UTC().AddDate(0, 0, -cfg.AgentInactiveDays).Format(time.RFC3339)
 		}
 
-		// Find matches.
-		matches, err := core.FindMatches(database, queryEmbedding, agent.ID, cfg.ScanMaxResults, cfg.ScanMinScore, heartbeatCutoff)
+		// Skip counterparts this task has already talked to, and blocked agents.
+		contacted, err := core.ContactedTasks(database, taskInternalID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"message": "Failed to look up conversations",
+			})
+			return
+		}
+		blocked, err := core.BlockedAgents(database, agent.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"message": "Failed to look up blocked agents",
+			})
+			return
+		}
+
+		origin, err := core.TaskLocation(database, taskInternalID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"message": "Failed to look up task location",
+			})
+			return
+		}
+
+		reciprocalTaskID := ""
+		if cfg.ScanReciprocal {
+			reciprocalTaskID = taskInternalID
+		}
+
+		// Find matches: radar sees beacons, beacons see radars, and only task types
+		// the compatibility matrix pairs with this one.
+		matches, incompatible, err := core.FindMatches(database, index, queryEmbedding, core.MatchOptions{
+			Mode:             core.ComplementaryMode(taskMode),
+			TypeRules:        core.CompatibleTaskTypes(cfg.TaskTypeCompatibility, taskType),
+			ExcludeAgentIDs:  append(blocked, agent.ID),
+			Contacted:        contacted,
+			IncludeContacted: req.IncludeContacted,
+			MaxResults:       cfg.ScanMaxResults,
+			MinScore:         cfg.ScanMinScore,
+			HeartbeatCutoff:  heartbeatCutoff,
+			Keywords:         keywords,
+			LexicalWeight:    cfg.LexicalWeight,
+			Filters:          req.Filters,
+			Origin:           origin,
+			ReciprocalTaskID: reciprocalTaskID,
+			Priors:           priors,
+			MaxPerAgent:      cfg.ScanMaxPerAgent,
+			Diversity:        cfg.ScanDiversity,
+		})
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error":   "matching_error",
@@ -88,32 +224,43 @@ func Scan(database *sql.DB, cfg *config.Config, embClient *core.EmbeddingClient)
 			})
 			return
 		}
-
-		// Filter by complementary mode: radar sees beacons, beacons see radars.
-		var complementaryMode string
-		if taskMode == "radar" {
-			complementaryMode = "beacon"
-		} else {
-			complementaryMode = "radar"
+		if incompatible > 0 {
+			log.Printf("Scan: skipped %d task embeddings from a different embedding model (re-embedding pending)", incompatible)
 		}
 
-		var filtered []core.MatchResult
-		for _, m := range matches {
-			if m.Mode == complementaryMode {
-				filtered = append(filtered, m)
-			}
+		if err := core.ExplainMatches(database, embClient, keywords, cfg.LexicalWeight, matches); err != nil {
+			log.Printf("Scan: failed to explain matches: %v", err)
 		}
 
-		if filtered == nil {
-			filtered = []core.MatchResult{}
+		if matches == nil {
+			matches = []core.MatchResult{}
 		}
 
 		// Suggest next scan time (60 seconds from now).
 		nextScanAfter := time.Now().UTC().Add(60 * time.Second).Format(time.RFC3339)
 
 		c.JSON(http.StatusOK, gin.H{
-			"matches":         filtered,
-			"next_scan_after": nextScanAfter,
+			"matches":              matches,
+			"next_scan_after":      nextScanAfter,
+			"skipped_incompatible": incompatible,
+			"search_mode":          searchMode,
+			"query_source":         querySource,
 		})
 	}
 }
+
+// mergeKeywords returns a followed by the keywords of b not already in a,
+// compared case-insensitively.
+func mergeKeywords(a, b []string) []string {
+	seen := make(map[string]struct{}, len(a)+len(b))
+	merged := make([]string, 0, len(a)+len(b))
+	for _, kw := range append(append([]string{}, a...), b...) {
+		key := strings.ToLower(strings.TrimSpace(kw))
+		if _, ok := seen[key]; ok || key == "" {
+			continue
+		}
+		seen[key] = struct{}{}
+		merged = append(merged, kw)
+	}
+	return merged
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestMergeKeywords(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []string
+		want []string
+	}{
+		{"appends new keywords", []string{"go"}, []string{"rust"}, []string{"go", "rust"}},
+		{"request keywords win", []string{"Go", "backend"}, []string{"go", "Backend ", "sql"}, []string{"Go", "backend", "sql"}},
+		{"duplicates within a list", []string{"go", "GO"}, []string{"rust", "Rust"}, []string{"go", "rust"}},
+		{"blank keywords dropped", []string{" ", "go"}, []string{""}, []string{"go"}},
+		{"no stored keywords", []string{"go"}, nil, []string{"go"}},
+		{"nothing", nil, nil, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeKeywords(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeKeywords(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanKeywordMode(t *testing.T) {
+	s := newTestServer(t)
+	_, token := s.register("seeker", TaskRequest{
+		TaskID: "r1", Mode: "radar", Type: "job-seeking", Title: "Job", Keywords: []string{"go", "backend"},
+	})
+
+	tests := []struct {
+		mode       string
+		wantCode   int
+		wantSource string
+	}{
+		{"", http.StatusOK, "keywords"},
+		{"replace", http.StatusOK, "keywords"},
+		{"blend", http.StatusOK, "blended"},
+		{"merge", http.StatusBadRequest, ""},
+		{"Blend", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			code, resp := s.do(http.MethodPost, "/api/v1/scan", token, ScanRequest{
+				TaskID: "r1", Keywords: []string{"rust"}, KeywordMode: tt.mode,
+			})
+			if code != tt.wantCode {
+				t.Fatalf("keyword_mode %q: %d %v, want %d", tt.mode, code, resp, tt.wantCode)
+			}
+			if code == http.StatusBadRequest && resp["error"] != "invalid_keyword_mode" {
+				t.Errorf("error = %v, want invalid_keyword_mode", resp["error"])
+			}
+			if code == http.StatusOK && resp["query_source"] != tt.wantSource {
+				t.Errorf("query_source = %v, want %s", resp["query_source"], tt.wantSource)
+			}
+		})
+	}
+}
@@ -3,50 +3,104 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
 
 // Config holds all application configuration values.
 type Config struct {
-	Port                      string
-	BaseURL                   string
-	SQLitePath                string
-	OpenAIAPIKey              string
-	OpenAIEmbeddingModel      string
-	OpenAIEmbeddingDimensions int
-	RegistrationDailyLimit    int
-	ScanMaxResults            int
-	ScanMinScore              float64
-	ReportBanThreshold        int
-	AdminEmail                string
-	TokenLength               int
-	AgentInactiveDays         int
-	ConversationTimeoutDays   int
-	MessageTTLDays            int
+	Port                              string
+	BaseURL                           string
+	SQLitePath                        string
+	EmbeddingProvider                 string
+	OpenAIAPIKey                      string
+	OpenAIEmbeddingModel              string
+	OpenAIEmbeddingDimensions         int
+	OpenAIBaseURL                     string
+	OpenAIExtraHeaders                map[string]string
+	OpenAIAPIVersion                  string
+	OpenAISendDimensions              bool
+	EmbeddingCacheEnabled             bool
+	EmbeddingCacheSize                int
+	EmbeddingCacheTTLDays             int
+	EmbeddingTimeoutSeconds           int
+	EmbeddingMaxRetries               int
+	EmbeddingBreakerThreshold         int
+	EmbeddingBreakerCooldownSeconds   int
+	EmbeddingMigrationBatchSize       int
+	EmbeddingMigrationIntervalSeconds int
+	EmbeddingRetryIntervalSeconds     int
+	StandingMatchIntervalSeconds      int
+	RegistrationDailyLimit            int
+	ScanMaxResults                    int
+	ScanMinScore                      float64
+	LexicalWeight                     float64
+	TaskTypeCompatibility             [][2]string
+	ScanReciprocal                    bool
+	ScanMaxPerAgent                   int
+	ScanDiversity                     float64
+	OutcomeRerankEnabled              bool
+	OutcomeRerankWeight               float64
+	OutcomeRerankIntervalSeconds      int
+	ContactEncryptionKey              string
+	ReportBanThreshold                int
+	AdminEmail                        string
+	TokenLength                       int
+	AgentInactiveDays                 int
+	ConversationTimeoutDays           int
+	MessageTTLDays                    int
 }
 
+// defaultTaskTypeCompatibility pairs task types that may match each other.
+const defaultTaskTypeCompatibility = "hiring:job-seeking,dating:dating,partnership:partnership,networking:networking,other:other"
+
 // Load reads configuration from environment variables (and .env file if present).
 func Load() *Config {
 	// Attempt to load .env file; ignore error if it doesn't exist.
 	_ = godotenv.Load()
 
 	cfg := &Config{
-		Port:                     getEnv("PORT", "8080"),
-		BaseURL:                  getEnv("BASE_URL", "http://localhost:8080"),
-		SQLitePath:               getEnv("SQLITE_PATH", "./data/agentsocial.db"),
-		OpenAIAPIKey:             getEnv("OPENAI_API_KEY", ""),
-		OpenAIEmbeddingModel:     getEnv("OPENAI_EMBEDDING_MODEL", "text-embedding-3-large"),
-		OpenAIEmbeddingDimensions: getEnvInt("OPENAI_EMBEDDING_DIMENSIONS", 256),
-		RegistrationDailyLimit:   getEnvInt("REGISTRATION_DAILY_LIMIT", 2),
-		ScanMaxResults:           getEnvInt("SCAN_MAX_RESULTS", 10),
-		ScanMinScore:             getEnvFloat("SCAN_MIN_SCORE", 0.7),
-		ReportBanThreshold:       getEnvInt("REPORT_BAN_THRESHOLD", 3),
-		AdminEmail:               getEnv("ADMIN_EMAIL", "admin@plaw.social"),
-		TokenLength:              getEnvInt("TOKEN_LENGTH", 32),
-		AgentInactiveDays:        getEnvInt("AGENT_INACTIVE_DAYS", 30),
-		ConversationTimeoutDays:  getEnvInt("CONVERSATION_TIMEOUT_DAYS", 7),
-		MessageTTLDays:           getEnvInt("MESSAGE_TTL_DAYS", 7),
+		Port:                              getEnv("PORT", "8080"),
+		BaseURL:                           getEnv("BASE_URL", "http://localhost:8080"),
+		SQLitePath:                        getEnv("SQLITE_PATH", "./data/agentsocial.db"),
+		EmbeddingProvider:                 getEnv("EMBEDDING_PROVIDER", "openai"),
+		OpenAIAPIKey:                      getEnv("OPENAI_API_KEY", ""),
+		OpenAIEmbeddingModel:              getEnv("OPENAI_EMBEDDING_MODEL", "text-embedding-3-large"),
+		OpenAIEmbeddingDimensions:         getEnvInt("OPENAI_EMBEDDING_DIMENSIONS", 256),
+		OpenAIBaseURL:                     getEnv("OPENAI_BASE_URL", "https://api.openai.com/v1"),
+		OpenAIExtraHeaders:                getEnvMap("OPENAI_EXTRA_HEADERS"),
+		OpenAIAPIVersion:                  getEnv("OPENAI_API_VERSION", ""),
+		OpenAISendDimensions:              getEnvBool("OPENAI_SEND_DIMENSIONS", true),
+		EmbeddingCacheEnabled:             getEnvBool("EMBEDDING_CACHE_ENABLED", true),
+		EmbeddingCacheSize:                getEnvInt("EMBEDDING_CACHE_SIZE", 4096),
+		EmbeddingCacheTTLDays:             getEnvInt("EMBEDDING_CACHE_TTL_DAYS", 30),
+		EmbeddingTimeoutSeconds:           getEnvInt("EMBEDDING_TIMEOUT_SECONDS", 10),
+		EmbeddingMaxRetries:               getEnvInt("EMBEDDING_MAX_RETRIES", 3),
+		EmbeddingBreakerThreshold:         getEnvInt("EMBEDDING_BREAKER_THRESHOLD", 5),
+		EmbeddingBreakerCooldownSeconds:   getEnvInt("EMBEDDING_BREAKER_COOLDOWN_SECONDS", 30),
+		EmbeddingMigrationBatchSize:       getEnvInt("EMBEDDING_MIGRATION_BATCH_SIZE", 20),
+		EmbeddingMigrationIntervalSeconds: getEnvInt("EMBEDDING_MIGRATION_INTERVAL_SECONDS", 30),
+		EmbeddingRetryIntervalSeconds:     getEnvInt("EMBEDDING_RETRY_INTERVAL_SECONDS", 30),
+		StandingMatchIntervalSeconds:      getEnvInt("STANDING_MATCH_INTERVAL_SECONDS", 5),
+		RegistrationDailyLimit:            getEnvInt("REGISTRATION_DAILY_LIMIT", 2),
+		ScanMaxResults:                    getEnvInt("SCAN_MAX_RESULTS", 10),
+		ScanMinScore:                      getEnvFloat("SCAN_MIN_SCORE", 0.7),
+		LexicalWeight:                     getEnvFloat("SCAN_LEXICAL_WEIGHT", 0.3),
+		TaskTypeCompatibility:             getEnvPairs("TASK_TYPE_COMPATIBILITY", defaultTaskTypeCompatibility),
+		ScanReciprocal:                    getEnvBool("SCAN_RECIPROCAL", false),
+		ScanMaxPerAgent:                   getEnvInt("SCAN_MAX_PER_AGENT", 0),
+		ScanDiversity:                     getEnvFloat("SCAN_DIVERSITY", 0),
+		OutcomeRerankEnabled:              getEnvBool("OUTCOME_RERANK_ENABLED", false),
+		OutcomeRerankWeight:               getEnvFloat("OUTCOME_RERANK_WEIGHT", 0.5),
+		OutcomeRerankIntervalSeconds:      getEnvInt("OUTCOME_RERANK_INTERVAL_SECONDS", 3600),
+		ContactEncryptionKey:              getEnv("CONTACT_ENCRYPTION_KEY", ""),
+		ReportBanThreshold:                getEnvInt("REPORT_BAN_THRESHOLD", 3),
+		AdminEmail:                        getEnv("ADMIN_EMAIL", "admin@plaw.social"),
+		TokenLength:                       getEnvInt("TOKEN_LENGTH", 32),
+		AgentInactiveDays:                 getEnvInt("AGENT_INACTIVE_DAYS", 30),
+		ConversationTimeoutDays:           getEnvInt("CONVERSATION_TIMEOUT_DAYS", 7),
+		MessageTTLDays:                    getEnvInt("MESSAGE_TTL_DAYS", 7),
 	}
 
 	return cfg
@@ -82,3 +136,45 @@ func getEnvFloat(key string, fallback float64) float64 {
 	}
 	return f
 }
+
+func getEnvBool(key string, fallback bool) bool {
+	val := getEnv(key, "")
+	if val == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		return fallback
+	}
+	return b
+}
+
+// getEnvMap parses a comma-separated list of "Name:Value" pairs.
+// Malformed entries are skipped.
+func getEnvMap(key string) map[string]string {
+	result := make(map[string]string)
+	for _, pair := range strings.Split(getEnv(key, ""), ",") {
+		name, value, ok := strings.Cut(pair, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			continue
+		}
+		result[name] = strings.TrimSpace(value)
+	}
+	return result
+}
+
+// getEnvPairs parses a comma-separated list of "A:B" pairs. Unlike getEnvMap,
+// a name may appear in several pairs. Malformed entries are skipped.
+func getEnvPairs(key, fallback string) [][2]string {
+	var result [][2]string
+	for _, pair := range strings.Split(getEnv(key, fallback), ",") {
+		a, b, ok := strings.Cut(pair, ":")
+		a, b = strings.TrimSpace(a), strings.TrimSpace(b)
+		if !ok || a == "" || b == "" {
+			continue
+		}
+		result = append(result, [2]string{a, b})
+	}
+	return result
+}
@@ -0,0 +1,241 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// AttributeKind is the value type of a structured task attribute.
+type AttributeKind string
+
+// Attribute kinds. A range is an object {"min": n, "max": n} with either bound optional.
+const (
+	AttributeString     AttributeKind = "string"
+	AttributeStringList AttributeKind = "string_list"
+	AttributeBool       AttributeKind = "bool"
+	AttributeNumber     AttributeKind = "number"
+	AttributeRange      AttributeKind = "range"
+)
+
+// attributeKinds defines every known attribute. An attribute has the same kind for
+// every task type so filters mean the same thing across types.
+var attributeKinds = map[string]AttributeKind{
+	"location":  AttributeString,
+	"remote":    AttributeBool,
+	"languages": AttributeStringList,
+	"salary":    AttributeRange,
+	"seniority": AttributeString,
+	"industry":  AttributeString,
+	"age":       AttributeNumber,
+	"age_range": AttributeRange,
+}
+
+// commonAttributes are allowed on every task type, including unknown ones.
+var commonAttributes = []string{"location", "remote", "languages"}
+
+// taskTypeAttributes lists the attributes each task type allows on top of commonAttributes.
+var taskTypeAttributes = map[string][]string{
+	"hiring":      {"salary", "seniority", "industry"},
+	"job-seeking": {"salary", "seniority", "industry"},
+	"dating":      {"age", "age_range"},
+	"partnership": {"industry"},
+	"networking":  {"industry"},
+}
+
+// ValidateTaskAttributes checks attributes against the schema of taskType.
+func ValidateTaskAttributes(taskType string, attrs map[string]interface{}) error {
+	allowed := toSet(append(append([]string{}, commonAttributes...), taskTypeAttributes[normalizeTaskType(taskType)]...))
+	for name, value := range attrs {
+		if _, ok := allowed[name]; !ok {
+			return fmt.Errorf("attribute %q is not allowed for task type %q (allowed: %s)", name, taskType, strings.Join(sortedKeys(allowed), ", "))
+		}
+		if err := checkAttributeValue(name, attributeKinds[name], value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateAttributeFilters checks that every filter names a known attribute and has
+// a value of a shape that attribute can be filtered by.
+func ValidateAttributeFilters(filters map[string]interface{}) error {
+	for name, value := range filters {
+		kind, ok := attributeKinds[name]
+		if !ok {
+			return fmt.Errorf("unknown filter attribute %q", name)
+		}
+		switch kind {
+		case AttributeString, AttributeStringList:
+			// A single value or a list of accepted values.
+			if _, isList := value.([]interface{}); isList {
+				if err := checkAttributeValue(name, AttributeStringList, value); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := checkAttributeValue(name, AttributeString, value); err != nil {
+				return err
+			}
+		case AttributeNumber, AttributeRange:
+			// A single number or a range.
+			if _, isNumber := value.(float64); isNumber {
+				continue
+			}
+			if err := checkAttributeValue(name, AttributeRange, value); err != nil {
+				return err
+			}
+		default:
+			if err := checkAttributeValue(name, kind, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func checkAttributeValue(name string, kind AttributeKind, value interface{}) error {
+	switch kind {
+	case AttributeString:
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("attribute %q must be a string", name)
+		}
+	case AttributeStringList:
+		list, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("attribute %q must be a list of strings", name)
+		}
+		for _, v := range list {
+			if _, ok := v.(string); !ok {
+				return fmt.Errorf("attribute %q must be a list of strings", name)
+			}
+		}
+	case AttributeBool:
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("attribute %q must be true or false", name)
+		}
+	case AttributeNumber:
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("attribute %q must be a number", name)
+		}
+	case AttributeRange:
+		lo, hi, ok := parseRange(value)
+		if !ok {
+			return fmt.Errorf(`attribute %q must be {"min": number, "max": number}`, name)
+		}
+		if lo > hi {
+			return fmt.Errorf("attribute %q has min greater than max", name)
+		}
+	}
+	return nil
+}
+
+// matchesAttributeFilters reports whether attrs satisfy every filter. Filters are
+// hard constraints: a task without the filtered attribute does not match.
+// Filters must have passed ValidateAttributeFilters.
+func matchesAttributeFilters(attrs, filters map[string]interface{}) bool {
+	for name, want := range filters {
+		have, ok := attrs[name]
+		if !ok {
+			return false
+		}
+		if !matchesAttributeFilter(attributeKinds[name], have, want) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesAttributeFilter(kind AttributeKind, have, want interface{}) bool {
+	switch kind {
+	case AttributeString, AttributeStringList:
+		// Any stored value equal to any wanted value, case-insensitively.
+		haveSet := toSet(lowerStrings(have))
+		for _, w := range lowerStrings(want) {
+			if _, ok := haveSet[w]; ok {
+				return true
+			}
+		}
+		return false
+	case AttributeBool:
+		return have == want
+	case AttributeNumber, AttributeRange:
+		// Overlap of the stored number or range with the wanted number or range.
+		haveLo, haveHi, ok := parseRange(have)
+		if !ok {
+			return false
+		}
+		wantLo, wantHi, ok := parseRange(want)
+		if !ok {
+			return false
+		}
+		return haveLo <= wantHi && wantLo <= haveHi
+	}
+	return false
+}
+
+// parseRange reads a number (a one-point range) or a {"min", "max"} object with
+// either bound optional. Missing bounds are open.
+func parseRange(value interface{}) (lo, hi float64, ok bool) {
+	if n, isNumber := value.(float64); isNumber {
+		return n, n, true
+	}
+	obj, isObject := value.(map[string]interface{})
+	if !isObject || len(obj) == 0 {
+		return 0, 0, false
+	}
+	lo, hi = math.Inf(-1), math.Inf(1)
+	for key, v := range obj {
+		n, isNumber := v.(float64)
+		if !isNumber {
+			return 0, 0, false
+		}
+		switch key {
+		case "min":
+			lo = n
+		case "max":
+			hi = n
+		default:
+			return 0, 0, false
+		}
+	}
+	return lo, hi, true
+}
+
+// lowerStrings returns a string or list of strings as a lowercased list.
+func lowerStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{strings.ToLower(strings.TrimSpace(v))}
+	case []interface{}:
+		out := make([]string, 0, len(v))
+		for _, s := range v {
+			if str, ok := s.(string); ok {
+				out = append(out, strings.ToLower(strings.TrimSpace(str)))
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// parseTaskAttributes decodes a task's stored attributes. Missing or malformed
+// attributes decode to nil, which satisfies no filter.
+func parseTaskAttributes(attrsJSON string) map[string]interface{} {
+	var attrs map[string]interface{}
+	if err := json.Unmarshal([]byte(attrsJSON), &attrs); err != nil {
+		return nil
+	}
+	return attrs
+}
+
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
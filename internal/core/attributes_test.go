@@ -0,0 +1,106 @@
+package core
+
+import (
+	"database/sql"
+	"encoding/json"
+	"testing"
+)
+
+func TestMatchesAttributeFilters(t *testing.T) {
+	attrs := parseTaskAttributes(`{"location": "Shanghai", "languages": ["Go", "English"], "remote": true, "salary": {"min": 30000, "max": 50000}, "age": 30}`)
+
+	tests := []struct {
+		name    string
+		filters string
+		want    bool
+	}{
+		{"string, case-insensitive", `{"location": "shanghai"}`, true},
+		{"string list, any value", `{"location": ["Beijing", "Shanghai"]}`, true},
+		{"string mismatch", `{"location": "Beijing"}`, false},
+		{"list attribute", `{"languages": "go"}`, true},
+		{"bool", `{"remote": true}`, true},
+		{"bool mismatch", `{"remote": false}`, false},
+		{"range overlap", `{"salary": {"min": 45000}}`, true},
+		{"range disjoint", `{"salary": {"min": 60000}}`, false},
+		{"number within range", `{"salary": 40000}`, true},
+		{"number as range", `{"age": {"min": 25, "max": 35}}`, true},
+		{"missing attribute", `{"industry": "tech"}`, false},
+		{"all must hold", `{"remote": true, "location": "Beijing"}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var filters map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.filters), &filters); err != nil {
+				t.Fatal(err)
+			}
+			if err := ValidateAttributeFilters(filters); err != nil {
+				t.Fatalf("ValidateAttributeFilters: %v", err)
+			}
+			if got := matchesAttributeFilters(attrs, filters); got != tt.want {
+				t.Errorf("matchesAttributeFilters = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTaskAttributesMalformed(t *testing.T) {
+	for _, s := range []string{"", "{}", "not json"} {
+		if matchesAttributeFilters(parseTaskAttributes(s), map[string]interface{}{"remote": true}) {
+			t.Errorf("attributes %q satisfied a filter", s)
+		}
+	}
+}
+
+// setTestAttributes stores a task's attributes and refreshes its index entry.
+func setTestAttributes(t *testing.T, database *sql.DB, index *VectorIndex, id, attrsJSON string) {
+	t.Helper()
+	if _, err := database.Exec("UPDATE tasks SET attributes = ? WHERE id = ?", attrsJSON, id); err != nil {
+		t.Fatal(err)
+	}
+	if err := index.Refresh(database, id); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFindMatchesFiltersOnIndexedAttributes(t *testing.T) {
+	database := openTestDB(t)
+	index := NewVectorIndex("m", 2)
+	insertTestAgent(t, database, "agent")
+	addTestBeacon(t, database, index, "remote", "agent", `["go"]`, unitAt(0.9))
+	addTestBeacon(t, database, index, "onsite", "agent", `["go"]`, unitAt(0.9))
+	addTestBeacon(t, database, index, "bare", "agent", `["go"]`, unitAt(0.9))
+	addTestBeacon(t, database, index, "remote-unembedded", "agent", `["go"]`, nil)
+	setTestAttributes(t, database, index, "remote", `{"remote": true}`)
+	setTestAttributes(t, database, index, "onsite", `{"remote": false}`)
+	setTestAttributes(t, database, index, "remote-unembedded", `{"remote": true}`)
+
+	find := func(embedding []float32) []string {
+		t.Helper()
+		results, _, err := FindMatches(database, index, embedding, MatchOptions{
+			Mode:          "beacon",
+			MaxResults:    10,
+			MinScore:      0.5,
+			Keywords:      []string{"go"},
+			LexicalWeight: 0.3,
+			Filters:       map[string]interface{}{"remote": true},
+		})
+		if err != nil {
+			t.Fatalf("FindMatches: %v", err)
+		}
+		return sortedIDs(results)
+	}
+
+	if got, want := find([]float32{1, 0}), []string{"remote"}; !equalIDs(got, want) {
+		t.Errorf("results = %v, want %v", got, want)
+	}
+	// Keyword-only hits are filtered the same way.
+	if got, want := find(nil), []string{"remote", "remote-unembedded"}; !equalIDs(got, want) {
+		t.Errorf("keyword-only results = %v, want %v", got, want)
+	}
+
+	// Changed attributes take effect once the index entry is refreshed.
+	setTestAttributes(t, database, index, "onsite", `{"remote": true}`)
+	if got, want := find([]float32{1, 0}), []string{"onsite", "remote"}; !equalIDs(got, want) {
+		t.Errorf("after update results = %v, want %v", got, want)
+	}
+}
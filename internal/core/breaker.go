@@ -0,0 +1,121 @@
+package core
+
+import (
+	"sync"
+	"time"
+)
+
+// Circuit breaker states.
+const (
+	breakerClosed   = "closed"
+	breakerOpen     = "open"
+	breakerHalfOpen = "half_open"
+)
+
+// circuitBreaker stops calling a failing provider for a cooldown period after
+// threshold consecutive failures. Once the cooldown elapses a single probe
+// request is let through (half-open): success closes the breaker, failure
+// re-opens it for another cooldown.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	state     string
+	failures  int
+	openedAt  time.Time
+	probing   bool
+	lastError string
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		state:     breakerClosed,
+	}
+}
+
+// allow reports whether a call may proceed. Every allowed call must be
+// followed by exactly one of success, failure or release.
+func (b *circuitBreaker) allow() error {
+	if b.threshold <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return ErrEmbeddingUnavailable
+		}
+		b.state = breakerHalfOpen
+		b.probing = true
+		return nil
+	case breakerHalfOpen:
+		if b.probing {
+			return ErrEmbeddingUnavailable
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// success records a successful call and closes the breaker.
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = breakerClosed
+	b.failures = 0
+	b.probing = false
+	b.lastError = ""
+}
+
+// failure records a provider failure, opening the breaker at the threshold
+// or immediately if the half-open probe failed.
+func (b *circuitBreaker) failure(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.lastError = err.Error()
+	b.probing = false
+
+	if b.threshold <= 0 {
+		return
+	}
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		b.state = breakerOpen
+		b.openedAt = time.Now()
+	}
+}
+
+// release ends a call whose outcome says nothing about provider health
+// (caller cancellation, invalid request), freeing a half-open probe slot.
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+// status returns a snapshot of the breaker for health reporting.
+func (b *circuitBreaker) status() EmbeddingStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := EmbeddingStatus{
+		Degraded:            b.state != breakerClosed,
+		Breaker:             b.state,
+		ConsecutiveFailures: b.failures,
+		LastError:           b.lastError,
+	}
+	if b.state == breakerOpen {
+		status.RetryAt = b.openedAt.Add(b.cooldown).UTC().Format(time.RFC3339)
+	}
+	return status
+}
@@ -0,0 +1,87 @@
+package core
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerOpensAtThreshold(t *testing.T) {
+	b := newCircuitBreaker(2, time.Hour)
+	errBoom := errors.New("boom")
+
+	for i := 0; i < 2; i++ {
+		if err := b.allow(); err != nil {
+			t.Fatalf("call %d rejected before threshold: %v", i, err)
+		}
+		b.failure(errBoom)
+	}
+
+	if err := b.allow(); !errors.Is(err, ErrEmbeddingUnavailable) {
+		t.Fatalf("allow after threshold = %v, want ErrEmbeddingUnavailable", err)
+	}
+	status := b.status()
+	if status.Breaker != breakerOpen || !status.Degraded || status.ConsecutiveFailures != 2 || status.LastError != "boom" || status.RetryAt == "" {
+		t.Errorf("status = %+v", status)
+	}
+}
+
+func TestCircuitBreakerSuccessResetsFailures(t *testing.T) {
+	b := newCircuitBreaker(2, time.Hour)
+	_ = b.allow()
+	b.failure(errors.New("boom"))
+	_ = b.allow()
+	b.success()
+	_ = b.allow()
+	b.failure(errors.New("boom"))
+
+	if err := b.allow(); err != nil {
+		t.Fatalf("breaker opened after non-consecutive failures: %v", err)
+	}
+}
+
+func TestCircuitBreakerHalfOpenProbe(t *testing.T) {
+	tests := []struct {
+		name      string
+		outcome   func(b *circuitBreaker)
+		wantState string
+		wantAllow bool
+	}{
+		{"probe succeeds", func(b *circuitBreaker) { b.success() }, breakerClosed, true},
+		{"probe fails", func(b *circuitBreaker) { b.failure(errors.New("boom")) }, breakerOpen, false},
+		{"probe released", func(b *circuitBreaker) { b.release() }, breakerHalfOpen, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newCircuitBreaker(1, time.Hour)
+			_ = b.allow()
+			b.failure(errors.New("boom"))
+			b.openedAt = time.Now().Add(-2 * time.Hour) // cooldown elapsed
+
+			if err := b.allow(); err != nil {
+				t.Fatalf("probe rejected: %v", err)
+			}
+			if err := b.allow(); !errors.Is(err, ErrEmbeddingUnavailable) {
+				t.Fatalf("second call during probe = %v, want ErrEmbeddingUnavailable", err)
+			}
+
+			tt.outcome(b)
+			if b.state != tt.wantState {
+				t.Errorf("state = %q, want %q", b.state, tt.wantState)
+			}
+			if err := b.allow(); (err == nil) != tt.wantAllow {
+				t.Errorf("allow after probe = %v, want allowed %v", err, tt.wantAllow)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	b := newCircuitBreaker(0, time.Hour)
+	for i := 0; i < 10; i++ {
+		if err := b.allow(); err != nil {
+			t.Fatalf("disabled breaker rejected call %d: %v", i, err)
+		}
+		b.failure(errors.New("boom"))
+	}
+}
@@ -25,10 +25,13 @@ func runCleanup(db *sql.DB, cfg *config.Config) {
 	hibernated := hibernateInactiveAgents(db, now, cfg.AgentInactiveDays)
 	expired := expirePendingConversations(db, now, cfg.ConversationTimeoutDays)
 	cleaned := cleanOrphanMessages(db, now, cfg.MessageTTLDays)
+	evicted := evictStaleEmbeddingCache(db, now, cfg.EmbeddingCacheTTLDays)
+	matches := cleanStaleMatchNotifications(db, now, cfg.MessageTTLDays)
+	notices := cleanStaleConversationNotifications(db, now, cfg.MessageTTLDays)
 
-	if hibernated > 0 || expired > 0 || cleaned > 0 {
-		log.Printf("Cleanup: hibernated %d agents, expired %d conversations, cleaned %d messages",
-			hibernated, expired, cleaned)
+	if hibernated > 0 || expired > 0 || cleaned > 0 || evicted > 0 || matches > 0 || notices > 0 {
+		log.Printf("Cleanup: hibernated %d agents, expired %d conversations, cleaned %d messages, evicted %d cached embeddings, cleaned %d match notifications, cleaned %d conversation notifications",
+			hibernated, expired, cleaned, evicted, matches, notices)
 	}
 }
 
@@ -82,17 +85,31 @@ func expirePendingConversations(db *sql.DB, now time.Time, timeoutDays int) int6
 
 	cutoff := now.AddDate(0, 0, -timeoutDays).Format(time.RFC3339)
 
-	result, err := db.Exec(
-		`UPDATE conversations SET state = 'expired', updated_at = ?
-		 WHERE state = 'pending_acceptance' AND created_at < ?`,
-		now.Format(time.RFC3339), cutoff,
+	rows, err := db.Query(
+		"SELECT id FROM conversations WHERE state = ? AND created_at < ?",
+		StatePendingAcceptance, cutoff,
 	)
 	if err != nil {
 		log.Printf("Cleanup error (expire conversations): %v", err)
 		return 0
 	}
-
-	count, _ := result.RowsAffected()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	rows.Close()
+
+	// Through the state machine, so each expiry is recorded and a request answered
+	// in the meantime is left alone.
+	var count int64
+	for _, id := range ids {
+		if _, err := TransitionConversation(db, id, ActorSystem, EventExpire, ""); err == nil {
+			count++
+		}
+	}
 	return count
 }
 
@@ -119,3 +136,68 @@ func cleanOrphanMessages(db *sql.DB, now time.Time, ttlDays int) int64 {
 	count, _ := result.RowsAffected()
 	return count
 }
+
+// evictStaleEmbeddingCache deletes cached embeddings not used in N days.
+// Returns count deleted.
+func evictStaleEmbeddingCache(db *sql.DB, now time.Time, ttlDays int) int64 {
+	if ttlDays <= 0 {
+		return 0
+	}
+
+	cutoff := now.AddDate(0, 0, -ttlDays).Format(time.RFC3339)
+
+	result, err := db.Exec("DELETE FROM embedding_cache WHERE last_used_at < ?", cutoff)
+	if err != nil {
+		log.Printf("Cleanup error (evict embedding cache): %v", err)
+		return 0
+	}
+
+	count, _ := result.RowsAffected()
+	return count
+}
+
+// cleanStaleMatchNotifications deletes match notifications older than N days that
+// were never picked up, or whose standing search has ended. Delivered notifications
+// of live standing searches are kept so the same pair is not notified twice.
+// Returns count deleted.
+func cleanStaleMatchNotifications(db *sql.DB, now time.Time, ttlDays int) int64 {
+	if ttlDays <= 0 {
+		return 0
+	}
+
+	cutoff := now.AddDate(0, 0, -ttlDays).Format(time.RFC3339)
+
+	result, err := db.Exec(
+		`DELETE FROM match_notifications
+		 WHERE created_at < ?
+		   AND (delivered_at IS NULL
+		     OR task_id NOT IN (SELECT id FROM tasks WHERE standing = 1 AND status = 'active'))`,
+		cutoff,
+	)
+	if err != nil {
+		log.Printf("Cleanup error (clean match notifications): %v", err)
+		return 0
+	}
+
+	count, _ := result.RowsAffected()
+	return count
+}
+
+// cleanStaleConversationNotifications deletes conversation notifications older than
+// N days, delivered or not. Returns count deleted.
+func cleanStaleConversationNotifications(db *sql.DB, now time.Time, ttlDays int) int64 {
+	if ttlDays <= 0 {
+		return 0
+	}
+
+	cutoff := now.AddDate(0, 0, -ttlDays).Format(time.RFC3339)
+
+	result, err := db.Exec("DELETE FROM conversation_notifications WHERE created_at < ?", cutoff)
+	if err != nil {
+		log.Printf("Cleanup error (clean conversation notifications): %v", err)
+		return 0
+	}
+
+	count, _ := result.RowsAffected()
+	return count
+}
@@ -0,0 +1,413 @@
+package core
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// Contact card limits.
+const (
+	maxContactCardFields      = 10
+	maxContactCardKeyLength   = 32
+	maxContactCardValueLength = 200
+)
+
+// Contact exchange notification types.
+const (
+	NotificationContactConsentGiven   = "contact_consent_given"
+	NotificationContactConsentRevoked = "contact_consent_revoked"
+	NotificationContactCard           = "contact_card"
+)
+
+// ErrContactCardNotFound is returned when an agent has no contact card for a task.
+var ErrContactCardNotFound = errors.New("contact card not found")
+
+// ContactExchangeError reports a consent change that the conversation does not allow.
+type ContactExchangeError struct {
+	Reason string
+}
+
+func (e *ContactExchangeError) Error() string {
+	return "contact exchange not allowed: " + e.Reason
+}
+
+// ContactVault encrypts contact cards at rest with AES-256-GCM.
+type ContactVault struct {
+	aead cipher.AEAD
+}
+
+// NewContactVault creates a vault from a 32-byte key given as base64 or hex.
+func NewContactVault(key string) (*ContactVault, error) {
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(raw) != 32 {
+		raw, err = hex.DecodeString(key)
+	}
+	if err != nil || len(raw) != 32 {
+		return nil, errors.New("contact encryption key must be 32 bytes, base64 or hex encoded")
+	}
+
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &ContactVault{aead: aead}, nil
+}
+
+// Seal encrypts a contact card. The random nonce is prepended to the ciphertext.
+func (v *ContactVault) Seal(card map[string]string) ([]byte, error) {
+	plaintext, err := json.Marshal(card)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, v.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return v.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Open decrypts a card sealed by Seal.
+func (v *ContactVault) Open(sealed []byte) (map[string]string, error) {
+	n := v.aead.NonceSize()
+	if len(sealed) < n {
+		return nil, errors.New("sealed contact card is too short")
+	}
+	plaintext, err := v.aead.Open(nil, sealed[:n], sealed[n:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt contact card: %w", err)
+	}
+	var card map[string]string
+	if err := json.Unmarshal(plaintext, &card); err != nil {
+		return nil, fmt.Errorf("failed to decode contact card: %w", err)
+	}
+	return card, nil
+}
+
+// ValidateContactCard checks a card's size as it will be stored: keys and values
+// trimmed, and fields with empty values dropped. Keys are free-form labels such as
+// "email" or "wechat".
+func ValidateContactCard(card map[string]string) error {
+	card = cleanContactCard(card)
+	fields := len(card)
+	for key, value := range card {
+		if key == "" || len(key) > maxContactCardKeyLength {
+			return fmt.Errorf("contact card field names must be 1-%d characters", maxContactCardKeyLength)
+		}
+		if len(value) > maxContactCardValueLength {
+			return fmt.Errorf("contact card field %q is longer than %d characters", key, maxContactCardValueLength)
+		}
+	}
+	if fields == 0 {
+		return errors.New("contact card has no fields")
+	}
+	if fields > maxContactCardFields {
+		return fmt.Errorf("contact card has more than %d fields", maxContactCardFields)
+	}
+	return nil
+}
+
+// cleanContactCard trims keys and values and drops fields with empty values.
+func cleanContactCard(card map[string]string) map[string]string {
+	cleaned := make(map[string]string, len(card))
+	for key, value := range card {
+		if value = strings.TrimSpace(value); value != "" {
+			cleaned[strings.TrimSpace(key)] = value
+		}
+	}
+	return cleaned
+}
+
+// SaveContactCard encrypts and stores an agent's contact card. taskID is a task's
+// internal ID, or "" for the agent's default card used by tasks without their own.
+func SaveContactCard(db *sql.DB, vault *ContactVault, agentID, taskID string, card map[string]string) error {
+	sealed, err := vault.Seal(cleanContactCard(card))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	_, err = db.Exec(
+		`INSERT INTO contact_cards (id, agent_id, task_id, ciphertext, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(id) DO UPDATE SET ciphertext = excluded.ciphertext, updated_at = excluded.updated_at`,
+		GenerateMD5(agentID, taskID), agentID, taskID, sealed, now, now,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store contact card: %w", err)
+	}
+	return nil
+}
+
+// DeleteContactCard removes a stored card. Returns ErrContactCardNotFound if there was none.
+func DeleteContactCard(db *sql.DB, agentID, taskID string) error {
+	result, err := db.Exec("DELETE FROM contact_cards WHERE id = ?", GenerateMD5(agentID, taskID))
+	if err != nil {
+		return fmt.Errorf("failed to delete contact card: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrContactCardNotFound
+	}
+	return nil
+}
+
+// LoadContactCard returns the card an agent shares for a task: the task's own card,
+// or else the agent's default card. Returns ErrContactCardNotFound if neither exists.
+func LoadContactCard(db *sql.DB, vault *ContactVault, agentID, taskID string) (map[string]string, error) {
+	sealed, err := sealedContactCard(db, agentID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	return vault.Open(sealed)
+}
+
+func sealedContactCard(db execer, agentID, taskID string) ([]byte, error) {
+	var sealed []byte
+	err := db.QueryRow(
+		`SELECT ciphertext FROM contact_cards
+		 WHERE agent_id = ? AND task_id IN (?, '')
+		 ORDER BY task_id DESC LIMIT 1`,
+		agentID, taskID,
+	).Scan(&sealed)
+	if err == sql.ErrNoRows {
+		return nil, ErrContactCardNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up contact card: %w", err)
+	}
+	return sealed, nil
+}
+
+// ContactExchangeStatus is the state of a conversation's contact exchange.
+type ContactExchangeStatus struct {
+	ConsentedBy []string `json:"consented_by"`
+	Released    bool     `json:"released"`
+	ReleasedAt  string   `json:"released_at,omitempty"`
+}
+
+// GiveContactConsent records agentID's consent to exchange contact cards in a round 3
+// conversation, together with a snapshot of the card the agent shares for its task,
+// so a stored card is required. Once both participants have consented, each snapshot
+// is queued as a contact_card notification for the other side and the exchange is final.
+// Consent, revocation and release each run in one transaction, so a release always
+// sees the consents as they are when it commits and always delivers both cards.
+func GiveContactConsent(db *sql.DB, conversationID, agentID string) (*ContactExchangeStatus, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	conv, err := loadContactConversation(tx, conversationID, agentID)
+	if err != nil {
+		return nil, err
+	}
+	if conv.state != "active" || conv.round != RoundHumanHuman {
+		return nil, &ContactExchangeError{Reason: "contact cards are only exchanged in round 3 of an active conversation"}
+	}
+	if conv.releasedAt.Valid {
+		return contactExchangeStatus(tx, conversationID)
+	}
+	sealed, err := sealedContactCard(tx, agentID, conv.myTask)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	result, err := tx.Exec(
+		"INSERT OR IGNORE INTO contact_consents (conversation_id, agent_id, ciphertext, created_at) VALUES (?, ?, ?, ?)",
+		conversationID, agentID, sealed, now,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record contact consent: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		notifyContactExchange(tx, conversationID, conv.counterpart, agentID, NotificationContactConsentGiven,
+			"The other side agreed to exchange contact cards; give your consent to receive theirs")
+	}
+
+	if err := releaseContactCards(tx, conversationID, now); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit contact consent: %w", err)
+	}
+
+	return contactExchangeStatus(db, conversationID)
+}
+
+// RevokeContactConsent withdraws agentID's consent. Not possible once the cards
+// have been released.
+func RevokeContactConsent(db *sql.DB, conversationID, agentID string) (*ContactExchangeStatus, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	conv, err := loadContactConversation(tx, conversationID, agentID)
+	if err != nil {
+		return nil, err
+	}
+	if conv.releasedAt.Valid {
+		return nil, &ContactExchangeError{Reason: "contact cards have already been released"}
+	}
+
+	result, err := tx.Exec(
+		"DELETE FROM contact_consents WHERE conversation_id = ? AND agent_id = ?",
+		conversationID, agentID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke contact consent: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		notifyContactExchange(tx, conversationID, conv.counterpart, agentID, NotificationContactConsentRevoked,
+			"The other side withdrew their consent to exchange contact cards")
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit contact consent: %w", err)
+	}
+
+	return contactExchangeStatus(db, conversationID)
+}
+
+// contactConversation is the part of a conversation the contact exchange needs,
+// seen from one participant.
+type contactConversation struct {
+	state       string
+	round       int
+	myTask      string
+	counterpart string
+	releasedAt  sql.NullString
+}
+
+func loadContactConversation(db execer, conversationID, agentID string) (*contactConversation, error) {
+	var initiatorAgent, targetAgent, initiatorTask, targetTask string
+	conv := &contactConversation{}
+	err := db.QueryRow(
+		`SELECT initiator_agent, target_agent, initiator_task, target_task, state, round, contacts_released_at
+		 FROM conversations WHERE id = ?`,
+		conversationID,
+	).Scan(&initiatorAgent, &targetAgent, &initiatorTask, &targetTask, &conv.state, &conv.round, &conv.releasedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up conversation: %w", err)
+	}
+
+	if agentID == initiatorAgent {
+		conv.myTask, conv.counterpart = initiatorTask, targetAgent
+	} else {
+		conv.myTask, conv.counterpart = targetTask, initiatorAgent
+	}
+	return conv, nil
+}
+
+// releaseContactCards queues each consenting side's card snapshot for the other once
+// both have consented, then marks the exchange released and drops the snapshots.
+// It runs in the caller's transaction: the release is only committed together with
+// both cards. Only the call that sets the release timestamp queues the cards.
+func releaseContactCards(tx *sql.Tx, conversationID, now string) error {
+	rows, err := tx.Query("SELECT agent_id, ciphertext FROM contact_consents WHERE conversation_id = ?", conversationID)
+	if err != nil {
+		return fmt.Errorf("failed to query contact consents: %w", err)
+	}
+	type consent struct {
+		agentID string
+		sealed  []byte
+	}
+	var consents []consent
+	for rows.Next() {
+		var c consent
+		if err := rows.Scan(&c.agentID, &c.sealed); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan contact consent: %w", err)
+		}
+		consents = append(consents, c)
+	}
+	rows.Close()
+	if len(consents) != 2 {
+		return nil
+	}
+
+	result, err := tx.Exec(
+		`UPDATE conversations SET contacts_released_at = ?, updated_at = ?
+		 WHERE id = ? AND contacts_released_at IS NULL
+		   AND (SELECT COUNT(*) FROM contact_consents WHERE conversation_id = ?) = 2`,
+		now, now, conversationID, conversationID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to release contact cards: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil
+	}
+
+	for i, c := range consents {
+		recipient := consents[1-i].agentID
+		if err := queueContactCard(tx, conversationID, recipient, c.agentID, c.sealed, "Contact cards exchanged"); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec("UPDATE contact_consents SET ciphertext = NULL WHERE conversation_id = ?", conversationID); err != nil {
+		return fmt.Errorf("failed to drop contact card snapshots: %w", err)
+	}
+	return nil
+}
+
+func queueContactCard(db execer, conversationID, agentID, fromAgentID string, sealed []byte, message string) error {
+	now := time.Now().UTC()
+	_, err := db.Exec(
+		`INSERT INTO conversation_notifications (id, conversation_id, agent_id, from_agent_id, type, message, payload, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		GenerateMD5(conversationID, agentID, NotificationContactCard, now.Format(time.RFC3339Nano)),
+		conversationID, agentID, fromAgentID, NotificationContactCard, message, sealed, now.Format(time.RFC3339),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to queue contact card: %w", err)
+	}
+	return nil
+}
+
+// notifyContactExchange queues a consent notice. The consent change stands even if
+// the counterpart cannot be notified.
+func notifyContactExchange(db execer, conversationID, agentID, fromAgentID, notificationType, message string) {
+	if err := queueConversationNotification(db, conversationID, agentID, fromAgentID, notificationType, message, ""); err != nil {
+		log.Printf("Conversation %s: %v", conversationID, err)
+	}
+}
+
+func contactExchangeStatus(db execer, conversationID string) (*ContactExchangeStatus, error) {
+	status := &ContactExchangeStatus{ConsentedBy: []string{}}
+
+	var releasedAt sql.NullString
+	if err := db.QueryRow("SELECT contacts_released_at FROM conversations WHERE id = ?", conversationID).Scan(&releasedAt); err != nil {
+		return nil, fmt.Errorf("failed to look up conversation: %w", err)
+	}
+	status.Released, status.ReleasedAt = releasedAt.Valid, releasedAt.String
+
+	rows, err := db.Query("SELECT agent_id FROM contact_consents WHERE conversation_id = ? ORDER BY created_at", conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query contact consents: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var agentID string
+		if err := rows.Scan(&agentID); err != nil {
+			return nil, fmt.Errorf("failed to scan contact consent: %w", err)
+		}
+		status.ConsentedBy = append(status.ConsentedBy, agentID)
+	}
+	return status, rows.Err()
+}
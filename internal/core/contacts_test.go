@@ -0,0 +1,307 @@
+package core
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var testContactKey = bytes.Repeat([]byte{7}, 32)
+
+func testContactVault(t *testing.T) *ContactVault {
+	t.Helper()
+	vault, err := NewContactVault(base64.StdEncoding.EncodeToString(testContactKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return vault
+}
+
+func TestNewContactVaultKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		wantErr bool
+	}{
+		{"base64", base64.StdEncoding.EncodeToString(testContactKey), false},
+		{"hex", hex.EncodeToString(testContactKey), false},
+		{"too short", base64.StdEncoding.EncodeToString(testContactKey[:16]), true},
+		{"garbage", "not a key", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewContactVault(tt.key); (err != nil) != tt.wantErr {
+				t.Errorf("NewContactVault err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestContactVaultSealOpen(t *testing.T) {
+	vault := testContactVault(t)
+	card := map[string]string{"email": "a@example.com", "wechat": "a123"}
+
+	sealed, err := vault.Seal(card)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(sealed, []byte("a@example.com")) {
+		t.Error("sealed card contains the plaintext")
+	}
+	again, _ := vault.Seal(card)
+	if bytes.Equal(sealed, again) {
+		t.Error("two seals of the same card are identical; nonce not random")
+	}
+
+	opened, err := vault.Open(sealed)
+	if err != nil || opened["email"] != card["email"] || opened["wechat"] != card["wechat"] {
+		t.Fatalf("Open = %v, %v; want %v", opened, err, card)
+	}
+
+	otherVault, _ := NewContactVault(hex.EncodeToString(bytes.Repeat([]byte{8}, 32)))
+	tampered := append([]byte(nil), sealed...)
+	tampered[len(tampered)-1] ^= 1
+	tests := []struct {
+		name   string
+		vault  *ContactVault
+		sealed []byte
+	}{
+		{"tampered", vault, tampered},
+		{"other key", otherVault, sealed},
+		{"truncated", vault, sealed[:4]},
+	}
+	for _, tt := range tests {
+		if _, err := tt.vault.Open(tt.sealed); err == nil {
+			t.Errorf("%s: Open succeeded", tt.name)
+		}
+	}
+}
+
+// setupContactExchange opens an active round 3 conversation between "initiator" and
+// "target", both of whom have stored a contact card.
+func setupContactExchange(t *testing.T) (*sql.DB, *ContactVault) {
+	t.Helper()
+	database := openTestDB(t)
+	vault := testContactVault(t)
+	insertTestParticipants(t, database)
+	insertTestConversation(t, database, "conv", StateActive)
+	if _, err := database.Exec("UPDATE conversations SET round = ? WHERE id = 'conv'", RoundHumanHuman); err != nil {
+		t.Fatal(err)
+	}
+	for _, agentID := range []string{"initiator", "target"} {
+		if err := SaveContactCard(database, vault, agentID, "", map[string]string{"email": agentID + "@example.com"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return database, vault
+}
+
+// deliveredCards decrypts the contact cards queued for each agent, keyed by recipient.
+func deliveredCards(t *testing.T, database *sql.DB, vault *ContactVault) map[string][]string {
+	t.Helper()
+	rows, err := database.Query("SELECT agent_id, payload FROM conversation_notifications WHERE type = ?", NotificationContactCard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	cards := make(map[string][]string)
+	for rows.Next() {
+		var agentID string
+		var payload []byte
+		if err := rows.Scan(&agentID, &payload); err != nil {
+			t.Fatal(err)
+		}
+		card, err := vault.Open(payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cards[agentID] = append(cards[agentID], card["email"])
+	}
+	return cards
+}
+
+func TestContactConsentSequence(t *testing.T) {
+	database, vault := setupContactExchange(t)
+
+	type step struct {
+		agentID      string
+		revoke       bool
+		wantErr      bool
+		wantReleased bool
+		wantConsents int
+	}
+	steps := []step{
+		{"initiator", false, false, false, 1},
+		{"initiator", false, false, false, 1}, // repeating is a no-op
+		{"initiator", true, false, false, 0},
+		{"target", false, false, false, 1},
+		{"initiator", false, false, true, 2},
+		{"initiator", true, true, true, 2}, // final once released
+		{"target", false, false, true, 2},
+	}
+	for i, s := range steps {
+		apply := GiveContactConsent
+		if s.revoke {
+			apply = RevokeContactConsent
+		}
+		status, err := apply(database, "conv", s.agentID)
+		var exchangeErr *ContactExchangeError
+		if s.wantErr != errors.As(err, &exchangeErr) {
+			t.Fatalf("step %d: err = %v, want ContactExchangeError %v", i, err, s.wantErr)
+		}
+		if err != nil {
+			continue
+		}
+		if status.Released != s.wantReleased || len(status.ConsentedBy) != s.wantConsents {
+			t.Fatalf("step %d: status = %+v, want released %v with %d consents", i, status, s.wantReleased, s.wantConsents)
+		}
+	}
+
+	cards := deliveredCards(t, database, vault)
+	if len(cards["initiator"]) != 1 || cards["initiator"][0] != "target@example.com" ||
+		len(cards["target"]) != 1 || cards["target"][0] != "initiator@example.com" {
+		t.Errorf("delivered cards = %v, want one card each from the other side", cards)
+	}
+}
+
+func TestGiveContactConsentPreconditions(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   string
+		wantErr func(error) bool
+	}{
+		{"not round 3", "UPDATE conversations SET round = 2", func(err error) bool {
+			var e *ContactExchangeError
+			return errors.As(err, &e)
+		}},
+		{"concluded", "UPDATE conversations SET state = 'concluded_matched'", func(err error) bool {
+			var e *ContactExchangeError
+			return errors.As(err, &e)
+		}},
+		{"no card", "DELETE FROM contact_cards WHERE agent_id = 'initiator'", func(err error) bool {
+			return errors.Is(err, ErrContactCardNotFound)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database, _ := setupContactExchange(t)
+			if _, err := database.Exec(tt.setup); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := GiveContactConsent(database, "conv", "initiator"); !tt.wantErr(err) {
+				t.Errorf("err = %v", err)
+			}
+		})
+	}
+}
+
+func TestContactReleaseRollsBackWhenCardsCannotBeQueued(t *testing.T) {
+	database, vault := setupContactExchange(t)
+	if _, err := GiveContactConsent(database, "conv", "initiator"); err != nil {
+		t.Fatal(err)
+	}
+	_, err := database.Exec(`CREATE TRIGGER fail_contact_cards BEFORE INSERT ON conversation_notifications
+		WHEN NEW.type = 'contact_card' BEGIN SELECT RAISE(ABORT, 'queue unavailable'); END`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := GiveContactConsent(database, "conv", "target"); err == nil {
+		t.Fatal("consent succeeded although the cards could not be queued")
+	}
+	status, err := contactExchangeStatus(database, "conv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Released || len(status.ConsentedBy) != 1 {
+		t.Fatalf("status = %+v, want unreleased with only the first consent", status)
+	}
+
+	// Nothing was marked released, so the first consent can still be withdrawn.
+	if _, err := RevokeContactConsent(database, "conv", "initiator"); err != nil {
+		t.Errorf("revoke after failed release: %v", err)
+	}
+	if cards := deliveredCards(t, database, vault); len(cards) != 0 {
+		t.Errorf("cards delivered: %v", cards)
+	}
+}
+
+func TestContactRevokeRacingRelease(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		database, vault := setupContactExchange(t)
+		if _, err := GiveContactConsent(database, "conv", "initiator"); err != nil {
+			t.Fatal(err)
+		}
+
+		var wg sync.WaitGroup
+		var consentErr, revokeErr error
+		wg.Add(2)
+		go func() { defer wg.Done(); _, consentErr = GiveContactConsent(database, "conv", "target") }()
+		go func() { defer wg.Done(); _, revokeErr = RevokeContactConsent(database, "conv", "initiator") }()
+		wg.Wait()
+		if consentErr != nil {
+			t.Fatalf("consent: %v", consentErr)
+		}
+
+		status, err := contactExchangeStatus(database, "conv")
+		if err != nil {
+			t.Fatal(err)
+		}
+		cards := deliveredCards(t, database, vault)
+		var exchangeErr *ContactExchangeError
+		switch {
+		case revokeErr == nil:
+			// The revoke won: nothing may be released.
+			if status.Released || len(cards) != 0 {
+				t.Fatalf("run %d: revoke confirmed but status = %+v, cards = %v", i, status, cards)
+			}
+		case errors.As(revokeErr, &exchangeErr):
+			// The release won: both cards must have been delivered.
+			if !status.Released || len(cards["initiator"]) != 1 || len(cards["target"]) != 1 {
+				t.Fatalf("run %d: released but status = %+v, cards = %v", i, status, cards)
+			}
+		default:
+			t.Fatalf("run %d: revoke: %v", i, revokeErr)
+		}
+	}
+}
+
+func TestValidateContactCard(t *testing.T) {
+	long := func(n int) string { return strings.Repeat("x", n) }
+	tests := []struct {
+		name    string
+		card    map[string]string
+		wantErr bool
+	}{
+		{"valid", map[string]string{"email": "a@example.com"}, false},
+		{"padded key at the limit", map[string]string{"  " + long(maxContactCardKeyLength) + " ": "v"}, false},
+		{"padded value at the limit", map[string]string{"email": " " + long(maxContactCardValueLength) + "\n"}, false},
+		{"key too long", map[string]string{long(maxContactCardKeyLength + 1): "v"}, true},
+		{"value too long", map[string]string{"email": long(maxContactCardValueLength + 1)}, true},
+		{"blank key", map[string]string{"  ": "v"}, true},
+		{"only blank values", map[string]string{"email": "  "}, true},
+		{"empty", map[string]string{}, true},
+		{"blank values do not count", map[string]string{"a": "1", "b": "  "}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateContactCard(tt.card); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateContactCard = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	tooMany := make(map[string]string)
+	for i := 0; i <= maxContactCardFields; i++ {
+		tooMany[fmt.Sprintf("k%d", i)] = "v"
+	}
+	if err := ValidateContactCard(tooMany); err == nil {
+		t.Error("card with too many fields accepted")
+	}
+}
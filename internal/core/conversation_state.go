@@ -0,0 +1,159 @@
+package core
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Conversation states.
+const (
+	StatePendingAcceptance = "pending_acceptance"
+	StateActive            = "active"
+	StateDeclined          = "declined"
+	StateConcludedMatched  = "concluded_matched"
+	StateConcludedNoMatch  = "concluded_no_match"
+	StateExpired           = "expired"
+)
+
+// Conversation lifecycle events.
+const (
+	EventAccept          = "accept"
+	EventDecline         = "decline"
+	EventConcludeMatched = "conclude_matched"
+	EventConcludeNoMatch = "conclude_no_match"
+	EventExpire          = "expire"
+	// EventCreate only appears in the history, as its first entry.
+	EventCreate = "create"
+)
+
+// ActorSystem is the actor recorded for transitions made by the platform itself.
+const ActorSystem = "system"
+
+// conversationTransition describes one event of the transition table.
+type conversationTransition struct {
+	from []string // states the event may be applied in
+	to   string   // state it leads to
+	// Round events only change the round, through ApplyRoundTransition. An
+	// escalation applies in fromRound and leads to toRound once confirmed; a zero
+	// fromRound allows any round.
+	round              bool
+	fromRound, toRound int
+}
+
+// conversationTransitions is the transition table: the states (and rounds) each
+// event may be applied in, and where it leads. Declined, concluded and expired
+// conversations are final.
+var conversationTransitions = map[string]conversationTransition{
+	EventAccept:          {from: []string{StatePendingAcceptance}, to: StateActive},
+	EventDecline:         {from: []string{StatePendingAcceptance}, to: StateDeclined},
+	EventConcludeMatched: {from: []string{StateActive}, to: StateConcludedMatched},
+	EventConcludeNoMatch: {from: []string{StatePendingAcceptance, StateActive}, to: StateConcludedNoMatch},
+	EventExpire:          {from: []string{StatePendingAcceptance}, to: StateExpired},
+
+	TransitionEscalateToRound2: {from: []string{StateActive}, to: StateActive, round: true, fromRound: RoundAgentAgent, toRound: RoundHumanAgent},
+	TransitionEscalateToRound3: {from: []string{StateActive}, to: StateActive, round: true, fromRound: RoundHumanAgent, toRound: RoundHumanHuman},
+	TransitionCancelEscalation: {from: []string{StateActive}, to: StateActive, round: true},
+}
+
+// checkTransition looks up event in the transition table and checks that it may be
+// applied to a conversation in state and round.
+func checkTransition(event, state string, round int) (conversationTransition, error) {
+	transition, ok := conversationTransitions[event]
+	if !ok {
+		return transition, ErrUnknownTransition
+	}
+	if !slices.Contains(transition.from, state) {
+		return transition, &TransitionError{Transition: event, State: state, Round: round, Reason: "the conversation is " + state}
+	}
+	if transition.fromRound != 0 && round != transition.fromRound {
+		return transition, &TransitionError{Transition: event, State: state, Round: round, Reason: fmt.Sprintf("the conversation is in round %d", round)}
+	}
+	return transition, nil
+}
+
+// ConversationAcceptsMessages reports whether messages may be sent in a conversation
+// in the given state.
+func ConversationAcceptsMessages(state string) bool {
+	return state == StatePendingAcceptance || state == StateActive
+}
+
+// TransitionConversation applies a lifecycle event on behalf of actor (an agent ID or
+// ActorSystem) and records it in the conversation's history, in one transaction.
+// reason is stored with the history entry, and as the decline reason for
+// EventDecline. A pending round escalation is dropped. Returns the new state,
+// ErrUnknownTransition for an unknown event (round events go through
+// ApplyRoundTransition), or a *TransitionError if the event is not allowed in the
+// conversation's current state. Callers check that the actor may trigger the event.
+// notices are queued from actor in the same transaction, so they are sent exactly
+// when the transition happens.
+func TransitionConversation(db *sql.DB, conversationID, actor, event, reason string, notices ...ConversationNotice) (string, error) {
+	if transition, ok := conversationTransitions[event]; !ok || transition.round {
+		return "", ErrUnknownTransition
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var state string
+	var round int
+	err = tx.QueryRow("SELECT state, round FROM conversations WHERE id = ?", conversationID).Scan(&state, &round)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up conversation: %w", err)
+	}
+	transition, err := checkTransition(event, state, round)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	query := `UPDATE conversations SET state = ?, pending_transition = '', transition_requested_by = '', updated_at = ?
+		 WHERE id = ? AND state = ?`
+	args := []interface{}{transition.to, now, conversationID, state}
+	if event == EventDecline {
+		query = `UPDATE conversations SET state = ?, pending_transition = '', transition_requested_by = '', updated_at = ?, decline_reason = ?
+		 WHERE id = ? AND state = ?`
+		args = []interface{}{transition.to, now, reason, conversationID, state}
+	}
+	if _, err := tx.Exec(query, args...); err != nil {
+		return "", fmt.Errorf("failed to update conversation state: %w", err)
+	}
+	if err := recordConversationTransition(tx, conversationID, event, state, transition.to, round, round, actor, reason, now); err != nil {
+		return "", err
+	}
+	for _, n := range notices {
+		if err := queueConversationNotification(tx, conversationID, n.AgentID, actor, n.Type, n.Message, n.Reason); err != nil {
+			return "", err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to commit conversation transition: %w", err)
+	}
+	return transition.to, nil
+}
+
+// RecordConversationCreated starts a new conversation's history, in the
+// transaction that inserts the conversation.
+func RecordConversationCreated(tx *sql.Tx, conversationID, actor string) error {
+	now := time.Now().UTC().Format(time.RFC3339)
+	return recordConversationTransition(tx, conversationID, EventCreate, "", StatePendingAcceptance, 0, RoundAgentAgent, actor, "", now)
+}
+
+// recordConversationTransition appends to the history. Callers write it in the
+// transaction that applies the transition.
+func recordConversationTransition(db execer, conversationID, event, fromState, toState string, fromRound, toRound int, actor, reason, now string) error {
+	_, err := db.Exec(
+		`INSERT INTO conversation_transitions (id, conversation_id, event, from_state, to_state, from_round, to_round, actor, reason, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		GenerateMD5(conversationID, event, actor, time.Now().UTC().Format(time.RFC3339Nano)),
+		conversationID, event, fromState, toState, fromRound, toRound, actor, reason, now,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record %s transition: %w", event, err)
+	}
+	return nil
+}
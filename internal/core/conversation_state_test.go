@@ -0,0 +1,197 @@
+package core
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// conversationHistory returns a conversation's history as "event from->to" entries,
+// with states for lifecycle events and rounds for round events, in insertion order.
+func conversationHistory(t *testing.T, database *sql.DB, id string) []string {
+	t.Helper()
+	rows, err := database.Query(
+		`SELECT event, from_state, to_state, from_round, to_round FROM conversation_transitions
+		 WHERE conversation_id = ? ORDER BY rowid`,
+		id,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var history []string
+	for rows.Next() {
+		var event, fromState, toState string
+		var fromRound, toRound int
+		if err := rows.Scan(&event, &fromState, &toState, &fromRound, &toRound); err != nil {
+			t.Fatal(err)
+		}
+		if conversationTransitions[event].round {
+			history = append(history, fmt.Sprintf("%s %d->%d", event, fromRound, toRound))
+		} else {
+			history = append(history, fmt.Sprintf("%s %s->%s", event, fromState, toState))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return history
+}
+
+func TestCheckTransition(t *testing.T) {
+	tests := []struct {
+		event   string
+		state   string
+		round   int
+		wantErr error // nil, ErrUnknownTransition, or a *TransitionError
+	}{
+		{EventAccept, StatePendingAcceptance, 1, nil},
+		{EventAccept, StateActive, 1, &TransitionError{}},
+		{EventDecline, StatePendingAcceptance, 1, nil},
+		{EventDecline, StateActive, 1, &TransitionError{}},
+		{EventConcludeMatched, StateActive, 2, nil},
+		{EventConcludeMatched, StatePendingAcceptance, 1, &TransitionError{}},
+		{EventConcludeMatched, StateConcludedNoMatch, 1, &TransitionError{}},
+		{EventConcludeNoMatch, StatePendingAcceptance, 1, nil},
+		{EventConcludeNoMatch, StateActive, 3, nil},
+		{EventConcludeNoMatch, StateExpired, 1, &TransitionError{}},
+		{EventExpire, StatePendingAcceptance, 1, nil},
+		{EventExpire, StateActive, 1, &TransitionError{}},
+		{TransitionEscalateToRound2, StateActive, 1, nil},
+		{TransitionEscalateToRound2, StateActive, 2, &TransitionError{}},
+		{TransitionEscalateToRound2, StatePendingAcceptance, 1, &TransitionError{}},
+		{TransitionEscalateToRound3, StateActive, 2, nil},
+		{TransitionEscalateToRound3, StateActive, 1, &TransitionError{}},
+		{TransitionEscalateToRound3, StateConcludedMatched, 2, &TransitionError{}},
+		{TransitionCancelEscalation, StateActive, 2, nil},
+		{TransitionCancelEscalation, StateDeclined, 1, &TransitionError{}},
+		{EventCreate, StatePendingAcceptance, 1, ErrUnknownTransition},
+		{"escalate_to_round4", StateActive, 3, ErrUnknownTransition},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s/%s/%d", tt.event, tt.state, tt.round), func(t *testing.T) {
+			_, err := checkTransition(tt.event, tt.state, tt.round)
+			var transitionErr *TransitionError
+			switch tt.wantErr.(type) {
+			case nil:
+				if err != nil {
+					t.Errorf("err = %v, want nil", err)
+				}
+			case *TransitionError:
+				if !errors.As(err, &transitionErr) || transitionErr.State != tt.state || transitionErr.Round != tt.round {
+					t.Errorf("err = %#v, want a TransitionError for %s in round %d", err, tt.state, tt.round)
+				}
+			default:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("err = %v, want %v", err, tt.wantErr)
+				}
+			}
+		})
+	}
+}
+
+func TestTransitionConversation(t *testing.T) {
+	tests := []struct {
+		name      string
+		state     string
+		event     string
+		wantState string // empty when the event is rejected
+	}{
+		{"accept", StatePendingAcceptance, EventAccept, StateActive},
+		{"decline", StatePendingAcceptance, EventDecline, StateDeclined},
+		{"withdraw", StatePendingAcceptance, EventConcludeNoMatch, StateConcludedNoMatch},
+		{"conclude matched", StateActive, EventConcludeMatched, StateConcludedMatched},
+		{"expire", StatePendingAcceptance, EventExpire, StateExpired},
+		{"conclude expired", StateExpired, EventConcludeNoMatch, ""},
+		{"conclude concluded", StateConcludedMatched, EventConcludeNoMatch, ""},
+		{"accept twice", StateActive, EventAccept, ""},
+		{"round event", StateActive, TransitionEscalateToRound2, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database := openTestDB(t)
+			insertTestParticipants(t, database)
+			insertTestConversation(t, database, "conv", tt.state)
+
+			got, err := TransitionConversation(database, "conv", "target", tt.event, "reason")
+			if tt.wantState == "" {
+				if err == nil {
+					t.Fatalf("%s from %s succeeded", tt.event, tt.state)
+				}
+				if history := conversationHistory(t, database, "conv"); len(history) != 0 {
+					t.Errorf("rejected event recorded: %v", history)
+				}
+				return
+			}
+			if err != nil || got != tt.wantState {
+				t.Fatalf("TransitionConversation = %q, %v; want %q", got, err, tt.wantState)
+			}
+
+			var state string
+			if err := database.QueryRow("SELECT state FROM conversations WHERE id = 'conv'").Scan(&state); err != nil {
+				t.Fatal(err)
+			}
+			want := []string{fmt.Sprintf("%s %s->%s", tt.event, tt.state, tt.wantState)}
+			if history := conversationHistory(t, database, "conv"); state != tt.wantState || !equalIDs(history, want) {
+				t.Errorf("state, history = %q, %v; want %q, %v", state, history, tt.wantState, want)
+			}
+		})
+	}
+}
+
+func TestConcludeDropsPendingEscalation(t *testing.T) {
+	database := openTestDB(t)
+	insertTestParticipants(t, database)
+	insertTestConversation(t, database, "conv", StateActive)
+	if _, err := ApplyRoundTransition(database, "conv", "initiator", TransitionEscalateToRound2); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := TransitionConversation(database, "conv", "target", EventConcludeNoMatch, ""); err != nil {
+		t.Fatal(err)
+	}
+	var pending, requestedBy string
+	err := database.QueryRow("SELECT pending_transition, transition_requested_by FROM conversations WHERE id = 'conv'").Scan(&pending, &requestedBy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending != "" || requestedBy != "" {
+		t.Errorf("pending escalation %q by %q survived the conclusion", pending, requestedBy)
+	}
+}
+
+func TestTransitionConversationQueuesNotices(t *testing.T) {
+	tests := []struct {
+		name  string
+		state string
+		want  int
+	}{
+		{"applied", StatePendingAcceptance, 1},
+		{"rejected", StateExpired, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database := openTestDB(t)
+			insertTestParticipants(t, database)
+			insertTestConversation(t, database, "conv", tt.state)
+
+			notice := ConversationNotice{AgentID: "initiator", Type: NotificationConversationDeclined, Message: "declined", Reason: "busy"}
+			_, _ = TransitionConversation(database, "conv", "target", EventDecline, "busy", notice)
+
+			var count int
+			err := database.QueryRow(
+				`SELECT COUNT(*) FROM conversation_notifications
+				 WHERE conversation_id = 'conv' AND agent_id = 'initiator' AND from_agent_id = 'target'
+				   AND type = ? AND reason = 'busy'`,
+				NotificationConversationDeclined,
+			).Scan(&count)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != tt.want {
+				t.Errorf("%d notifications queued, want %d", count, tt.want)
+			}
+		})
+	}
+}
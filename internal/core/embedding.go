@@ -2,39 +2,221 @@ package core
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"math"
+	"math/rand"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"agentsocial/internal/config"
+)
+
+const defaultOpenAIBaseURL = "https://api.openai.com/v1"
+
+// Embedding provider names accepted by EMBEDDING_PROVIDER.
+const (
+	EmbeddingProviderOpenAI = "openai"
+	EmbeddingProviderLocal  = "local"
+	EmbeddingProviderNone   = "none"
+)
+
+// Retry backoff bounds for transient embedding API failures.
+const (
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 10 * time.Second
 )
 
-const openAIEmbeddingsURL = "https://api.openai.com/v1/embeddings"
+// ErrEmbeddingUnavailable is returned without calling the provider while the
+// circuit breaker is open.
+var ErrEmbeddingUnavailable = errors.New("embedding provider is unavailable")
+
+// ErrEmbeddingNotConfigured is returned when no embedding provider is set up.
+// Matching then falls back to keyword-only search.
+var ErrEmbeddingNotConfigured = errors.New("no embedding provider is configured")
+
+// Embedder turns keyword text into a vector used for similarity matching.
+type Embedder interface {
+	GetEmbedding(ctx context.Context, text string) ([]float32, error)
+	// GetEmbeddings embeds several texts at once. The result is index-aligned with texts.
+	GetEmbeddings(ctx context.Context, texts []string) ([][]float32, error)
+	// Model and Dimensions identify the vector space the embeddings belong to.
+	Model() string
+	Dimensions() int
+	// Status reports whether the provider is currently healthy.
+	Status() EmbeddingStatus
+}
+
+// Matching modes reported in EmbeddingStatus.
+const (
+	EmbeddingModeSemantic    = "semantic"     // embeddings, blended with keyword search
+	EmbeddingModeKeywordOnly = "keyword_only" // no embedding provider; keyword search only
+)
+
+// EmbeddingStatus describes the health of the embedding provider.
+type EmbeddingStatus struct {
+	Provider            string `json:"provider"`
+	Mode                string `json:"mode"`
+	Model               string `json:"model"`
+	Dimensions          int    `json:"dimensions"`
+	Degraded            bool   `json:"degraded"`
+	Breaker             string `json:"breaker"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+	LastError           string `json:"last_error,omitempty"`
+	RetryAt             string `json:"retry_at,omitempty"`
+}
+
+// NewEmbedder returns the embedding backend selected by the configuration.
+// Unknown provider names fall back to OpenAI. Without an API key for the public
+// OpenAI endpoint there is no usable provider, and a NoopEmbedder is returned.
+func NewEmbedder(cfg *config.Config) Embedder {
+	switch {
+	case cfg.EmbeddingProvider == EmbeddingProviderLocal:
+		return NewLocalEmbedder(cfg.OpenAIEmbeddingDimensions)
+	case cfg.EmbeddingProvider == EmbeddingProviderNone:
+		return NoopEmbedder{}
+	case cfg.OpenAIAPIKey == "" && strings.HasPrefix(embeddingsEndpoint(cfg.OpenAIBaseURL, ""), defaultOpenAIBaseURL):
+		return NoopEmbedder{}
+	}
+	return NewEmbeddingClient(cfg.OpenAIAPIKey, cfg.OpenAIEmbeddingModel, cfg.OpenAIEmbeddingDimensions, EmbeddingClientOptions{
+		BaseURL:          cfg.OpenAIBaseURL,
+		Headers:          cfg.OpenAIExtraHeaders,
+		APIVersion:       cfg.OpenAIAPIVersion,
+		SendDimensions:   cfg.OpenAISendDimensions,
+		Timeout:          time.Duration(cfg.EmbeddingTimeoutSeconds) * time.Second,
+		MaxRetries:       cfg.EmbeddingMaxRetries,
+		BreakerThreshold: cfg.EmbeddingBreakerThreshold,
+		BreakerCooldown:  time.Duration(cfg.EmbeddingBreakerCooldownSeconds) * time.Second,
+	})
+}
+
+// NoopEmbedder stands in when no embedding provider is configured. Every call
+// fails with ErrEmbeddingNotConfigured, and scans use keyword search only.
+type NoopEmbedder struct{}
+
+// GetEmbedding always returns ErrEmbeddingNotConfigured.
+func (NoopEmbedder) GetEmbedding(ctx context.Context, text string) ([]float32, error) {
+	return nil, ErrEmbeddingNotConfigured
+}
 
-// EmbeddingClient handles communication with the OpenAI Embeddings API.
+// GetEmbeddings always returns ErrEmbeddingNotConfigured.
+func (NoopEmbedder) GetEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	return nil, ErrEmbeddingNotConfigured
+}
+
+// Model returns "none".
+func (NoopEmbedder) Model() string { return EmbeddingProviderNone }
+
+// Dimensions returns 0.
+func (NoopEmbedder) Dimensions() int { return 0 }
+
+// Status reports keyword-only matching. That is how the deployment is configured,
+// not a failure, so it is not degraded.
+func (NoopEmbedder) Status() EmbeddingStatus {
+	return EmbeddingStatus{
+		Provider: EmbeddingProviderNone,
+		Mode:     EmbeddingModeKeywordOnly,
+		Model:    EmbeddingProviderNone,
+		Breaker:  breakerClosed,
+	}
+}
+
+// EmbeddingClientOptions configures an OpenAI-compatible embeddings endpoint
+// (Azure OpenAI, vLLM, Ollama, LocalAI, ...).
+type EmbeddingClientOptions struct {
+	// BaseURL is the API root; "/embeddings" is appended. Defaults to OpenAI.
+	BaseURL string
+	// Headers are added to every request, e.g. Azure's "api-key".
+	Headers map[string]string
+	// APIVersion, if set, is sent as the "api-version" query parameter.
+	APIVersion string
+	// SendDimensions controls whether the "dimensions" field is sent.
+	// Some servers reject it.
+	SendDimensions bool
+	// Timeout bounds each HTTP attempt. Zero means no per-attempt timeout.
+	Timeout time.Duration
+	// MaxRetries is the number of retries on 429, 5xx and network errors.
+	MaxRetries int
+	// BreakerThreshold consecutive failures open the circuit breaker for
+	// BreakerCooldown. A threshold of zero disables the breaker.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+// EmbeddingClient handles communication with an OpenAI-compatible Embeddings API.
 type EmbeddingClient struct {
-	apiKey     string
-	model      string
-	dimensions int
-	httpClient *http.Client
+	apiKey         string
+	model          string
+	dimensions     int
+	endpoint       string
+	headers        map[string]string
+	sendDimensions bool
+	timeout        time.Duration
+	maxRetries     int
+	breaker        *circuitBreaker
+	httpClient     *http.Client
+	// dimensionWarning logs the first response with the wrong vector length.
+	dimensionWarning sync.Once
 }
 
 // NewEmbeddingClient creates a new client for generating text embeddings.
-func NewEmbeddingClient(apiKey, model string, dimensions int) *EmbeddingClient {
+func NewEmbeddingClient(apiKey, model string, dimensions int, opts EmbeddingClientOptions) *EmbeddingClient {
 	return &EmbeddingClient{
-		apiKey:     apiKey,
-		model:      model,
-		dimensions: dimensions,
-		httpClient: &http.Client{},
+		apiKey:         apiKey,
+		model:          model,
+		dimensions:     dimensions,
+		endpoint:       embeddingsEndpoint(opts.BaseURL, opts.APIVersion),
+		headers:        opts.Headers,
+		sendDimensions: opts.SendDimensions,
+		timeout:        opts.Timeout,
+		maxRetries:     opts.MaxRetries,
+		breaker:        newCircuitBreaker(opts.BreakerThreshold, opts.BreakerCooldown),
+		httpClient:     &http.Client{},
 	}
 }
 
+// Model returns the embedding model name.
+func (ec *EmbeddingClient) Model() string { return ec.model }
+
+// Dimensions returns the requested embedding size.
+func (ec *EmbeddingClient) Dimensions() int { return ec.dimensions }
+
+// Status reports the circuit breaker state of the remote provider.
+func (ec *EmbeddingClient) Status() EmbeddingStatus {
+	status := ec.breaker.status()
+	status.Provider = EmbeddingProviderOpenAI
+	status.Mode = EmbeddingModeSemantic
+	status.Model = ec.model
+	status.Dimensions = ec.dimensions
+	return status
+}
+
+// embeddingsEndpoint builds the full embeddings URL from a base URL and optional API version.
+func embeddingsEndpoint(baseURL, apiVersion string) string {
+	if baseURL == "" {
+		baseURL = defaultOpenAIBaseURL
+	}
+	endpoint := strings.TrimRight(baseURL, "/") + "/embeddings"
+	if apiVersion != "" {
+		endpoint += "?api-version=" + url.QueryEscape(apiVersion)
+	}
+	return endpoint
+}
+
 // embeddingRequest is the request body sent to the OpenAI API.
 type embeddingRequest struct {
-	Model      string `json:"model"`
-	Input      string `json:"input"`
-	Dimensions int    `json:"dimensions"`
+	Model      string   `json:"model"`
+	Input      []string `json:"input"`
+	Dimensions int      `json:"dimensions,omitempty"`
 }
 
 // embeddingResponse is the response from the OpenAI API.
@@ -53,44 +235,154 @@ type embeddingResponse struct {
 	} `json:"error,omitempty"`
 }
 
-// GetEmbedding calls the OpenAI Embeddings API and returns the embedding vector.
-func (ec *EmbeddingClient) GetEmbedding(text string) ([]float32, error) {
-	if ec.apiKey == "" {
-		return nil, fmt.Errorf("OpenAI API key is not configured")
+// retryableError marks a failure worth retrying (429, 5xx, network errors).
+// retryAfter carries the server's Retry-After hint, if any.
+type retryableError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+// GetEmbedding calls the embeddings API and returns the embedding vector.
+func (ec *EmbeddingClient) GetEmbedding(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := ec.GetEmbeddings(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// GetEmbeddings sends all texts in a single request and maps the returned
+// vectors back to their inputs by index. Transient failures are retried with
+// jittered exponential backoff; persistent ones trip the circuit breaker.
+func (ec *EmbeddingClient) GetEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return [][]float32{}, nil
+	}
+
+	// Self-hosted servers often need no key; only the public API requires one.
+	if ec.apiKey == "" && strings.HasPrefix(ec.endpoint, defaultOpenAIBaseURL) {
+		return nil, ErrEmbeddingNotConfigured
+	}
+
+	if err := ec.breaker.allow(); err != nil {
+		return nil, err
 	}
 
 	reqBody := embeddingRequest{
-		Model:      ec.model,
-		Input:      text,
-		Dimensions: ec.dimensions,
+		Model: ec.model,
+		Input: texts,
+	}
+	if ec.sendDimensions {
+		reqBody.Dimensions = ec.dimensions
 	}
 
 	jsonBody, err := json.Marshal(reqBody)
 	if err != nil {
+		ec.breaker.release()
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequest(http.MethodPost, openAIEmbeddingsURL, bytes.NewReader(jsonBody))
+	for attempt := 0; ; attempt++ {
+		embeddings, err := ec.doRequest(ctx, jsonBody, len(texts))
+		if err == nil {
+			ec.breaker.success()
+			// The provider is healthy but configured for another vector size;
+			// storing or querying these vectors would silently break search.
+			if err := ec.checkDimensions(embeddings); err != nil {
+				return nil, err
+			}
+			return embeddings, nil
+		}
+
+		// The caller gave up; that says nothing about the provider's health.
+		if ctx.Err() != nil {
+			ec.breaker.release()
+			return nil, err
+		}
+
+		var retryable *retryableError
+		if !errors.As(err, &retryable) {
+			ec.breaker.release()
+			return nil, err
+		}
+		if attempt >= ec.maxRetries {
+			ec.breaker.failure(err)
+			return nil, err
+		}
+
+		delay := backoffDelay(attempt, retryable.retryAfter)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			ec.breaker.failure(err)
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			ec.breaker.release()
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// checkDimensions rejects vectors whose length differs from the configured
+// dimensions, e.g. from a server that ignores the "dimensions" field.
+func (ec *EmbeddingClient) checkDimensions(embeddings [][]float32) error {
+	for _, embedding := range embeddings {
+		if len(embedding) == ec.dimensions {
+			continue
+		}
+		err := fmt.Errorf("embedding model %s returned %d-dimensional vectors, but %d are configured; set OPENAI_EMBEDDING_DIMENSIONS=%d",
+			ec.model, len(embedding), ec.dimensions, len(embedding))
+		ec.dimensionWarning.Do(func() {
+			log.Printf("ERROR: %v. Embeddings fail until the configuration matches the provider.", err)
+		})
+		return err
+	}
+	return nil
+}
+
+// doRequest performs a single HTTP attempt bounded by the per-attempt timeout.
+func (ec *EmbeddingClient) doRequest(ctx context.Context, jsonBody []byte, count int) ([][]float32, error) {
+	if ec.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, ec.timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ec.endpoint, bytes.NewReader(jsonBody))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+ec.apiKey)
+	if ec.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+ec.apiKey)
+	}
+	for name, value := range ec.headers {
+		req.Header.Set(name, value)
+	}
 
 	resp, err := ec.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to call OpenAI API: %w", err)
+		return nil, &retryableError{err: fmt.Errorf("failed to call embeddings API: %w", err)}
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, &retryableError{err: fmt.Errorf("failed to read response body: %w", err)}
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("OpenAI API returned status %d: %s", resp.StatusCode, string(body))
+		err := fmt.Errorf("embeddings API returned status %d: %s", resp.StatusCode, string(body))
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			return nil, &retryableError{err: err, retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+		}
+		return nil, err
 	}
 
 	var embResp embeddingResponse
@@ -99,21 +391,54 @@ func (ec *EmbeddingClient) GetEmbedding(text string) ([]float32, error) {
 	}
 
 	if embResp.Error != nil {
-		return nil, fmt.Errorf("OpenAI API error: %s", embResp.Error.Message)
+		return nil, fmt.Errorf("embeddings API error: %s", embResp.Error.Message)
+	}
+
+	if len(embResp.Data) != count {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", count, len(embResp.Data))
 	}
 
-	if len(embResp.Data) == 0 {
-		return nil, fmt.Errorf("no embedding data returned")
+	// Results may arrive in any order; place each by its index and convert float64 to float32.
+	embeddings := make([][]float32, count)
+	for _, d := range embResp.Data {
+		if d.Index < 0 || d.Index >= count || embeddings[d.Index] != nil {
+			return nil, fmt.Errorf("invalid embedding index %d in response", d.Index)
+		}
+		embedding := make([]float32, len(d.Embedding))
+		for i, v := range d.Embedding {
+			embedding[i] = float32(v)
+		}
+		embeddings[d.Index] = embedding
 	}
 
-	// Convert float64 to float32.
-	f64 := embResp.Data[0].Embedding
-	embedding := make([]float32, len(f64))
-	for i, v := range f64 {
-		embedding[i] = float32(v)
+	return embeddings, nil
+}
+
+// backoffDelay returns the wait before retry number attempt+1: the server's
+// Retry-After if given, otherwise exponential backoff with equal jitter.
+func backoffDelay(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return min(retryAfter, retryMaxDelay)
 	}
+	delay := min(retryBaseDelay<<attempt, retryMaxDelay)
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
 
-	return embedding, nil
+// parseRetryAfter understands both forms of the Retry-After header:
+// delay in seconds and an HTTP date. Returns 0 if absent or invalid.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
 }
 
 // EmbeddingToBytes converts a float32 slice to a binary blob for storage.
@@ -159,3 +484,17 @@ func CosineSimilarity(a, b []float32) float64 {
 
 	return dotProduct / denominator
 }
+
+// BlendEmbeddings averages two vectors after normalizing each, so both count
+// equally regardless of magnitude. Returns a if the sizes differ.
+func BlendEmbeddings(a, b []float32) []float32 {
+	if len(a) != len(b) {
+		return a
+	}
+	na, nb := normalize(a), normalize(b)
+	out := make([]float32, len(a))
+	for i := range out {
+		out[i] = (na[i] + nb[i]) / 2
+	}
+	return normalize(out)
+}
@@ -0,0 +1,224 @@
+package core
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CachedEmbedder wraps an Embedder with a content-addressed cache: an in-process
+// LRU backed by the embedding_cache table. Entries are keyed by a hash of
+// (model, dimensions, normalized text), so repeated scans with the same keywords
+// cost no API calls, even across restarts. The normalized text is also what gets
+// embedded, so a cached vector does not depend on which spelling missed first.
+type CachedEmbedder struct {
+	inner Embedder
+	db    *sql.DB
+	lru   *lruCache
+}
+
+// NewCachedEmbedder wraps inner with a cache holding up to lruSize vectors in memory.
+func NewCachedEmbedder(db *sql.DB, inner Embedder, lruSize int) *CachedEmbedder {
+	return &CachedEmbedder{
+		inner: inner,
+		db:    db,
+		lru:   newLRUCache(lruSize),
+	}
+}
+
+// Model returns the wrapped embedder's model name.
+func (ce *CachedEmbedder) Model() string { return ce.inner.Model() }
+
+// Dimensions returns the wrapped embedder's embedding size.
+func (ce *CachedEmbedder) Dimensions() int { return ce.inner.Dimensions() }
+
+// Status reports the wrapped embedder's health.
+func (ce *CachedEmbedder) Status() EmbeddingStatus { return ce.inner.Status() }
+
+// GetEmbedding returns the cached embedding for text, computing it on a miss.
+func (ce *CachedEmbedder) GetEmbedding(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := ce.GetEmbeddings(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// GetEmbeddings resolves each text from the LRU, then the database, and sends
+// only the remaining misses to the wrapped embedder in one batch.
+func (ce *CachedEmbedder) GetEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, len(texts))
+	keys := make([]string, len(texts))
+
+	// missing maps a cache key to the positions waiting on it, so duplicate
+	// texts in one batch are only embedded once.
+	missing := make(map[string][]int)
+	var missTexts, missKeys []string
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	for i, text := range texts {
+		key := EmbeddingCacheKey(ce.Model(), ce.Dimensions(), text)
+		keys[i] = key
+
+		if embedding, ok := ce.lru.get(key); ok {
+			embeddings[i] = embedding
+			continue
+		}
+		if embedding, ok := ce.load(key, now); ok {
+			ce.lru.put(key, embedding)
+			embeddings[i] = embedding
+			continue
+		}
+
+		if _, seen := missing[key]; !seen {
+			missTexts = append(missTexts, NormalizeEmbeddingText(text))
+			missKeys = append(missKeys, key)
+		}
+		missing[key] = append(missing[key], i)
+	}
+
+	if len(missTexts) == 0 {
+		return embeddings, nil
+	}
+
+	computed, err := ce.inner.GetEmbeddings(ctx, missTexts)
+	if err != nil {
+		return nil, err
+	}
+
+	for j, embedding := range computed {
+		key := missKeys[j]
+		ce.store(key, embedding, now)
+		ce.lru.put(key, embedding)
+		for _, i := range missing[key] {
+			embeddings[i] = embedding
+		}
+	}
+
+	return embeddings, nil
+}
+
+// LookupEmbeddings returns the vectors the cache already holds for texts, keyed by
+// normalized text. It never calls the wrapped embedder.
+func (ce *CachedEmbedder) LookupEmbeddings(texts []string) map[string][]float32 {
+	vectors := make(map[string][]float32)
+	now := time.Now().UTC().Format(time.RFC3339)
+	for _, text := range texts {
+		key := EmbeddingCacheKey(ce.Model(), ce.Dimensions(), text)
+		embedding, ok := ce.lru.get(key)
+		if !ok {
+			if embedding, ok = ce.load(key, now); ok {
+				ce.lru.put(key, embedding)
+			}
+		}
+		if ok {
+			vectors[NormalizeEmbeddingText(text)] = embedding
+		}
+	}
+	return vectors
+}
+
+// load reads an embedding from the database cache and refreshes its last-used time.
+func (ce *CachedEmbedder) load(key, now string) ([]float32, bool) {
+	var raw []byte
+	err := ce.db.QueryRow("SELECT embedding FROM embedding_cache WHERE cache_key = ?", key).Scan(&raw)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("WARNING: embedding cache lookup failed: %v", err)
+		}
+		return nil, false
+	}
+	_, _ = ce.db.Exec("UPDATE embedding_cache SET last_used_at = ? WHERE cache_key = ?", now, key)
+	return BytesToEmbedding(raw), true
+}
+
+// store persists an embedding in the database cache. Failures only cost a future recompute.
+func (ce *CachedEmbedder) store(key string, embedding []float32, now string) {
+	_, err := ce.db.Exec(
+		`INSERT OR REPLACE INTO embedding_cache (cache_key, model, dimensions, embedding, created_at, last_used_at)
+		 VALUES (?, ?, ?, ?, ?, ?)`,
+		key, ce.Model(), len(embedding), EmbeddingToBytes(embedding), now, now,
+	)
+	if err != nil {
+		log.Printf("WARNING: failed to store embedding in cache: %v", err)
+	}
+}
+
+// embeddingCacheKeyVersion is part of every cache key. Entries written before
+// normalized text was embedded used no version and age out unused.
+const embeddingCacheKeyVersion = "v2"
+
+// EmbeddingCacheKey returns the content address of an embedding: a SHA-256 over
+// the model, dimensions and normalized text.
+func EmbeddingCacheKey(model string, dimensions int, text string) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%d\x00%s", embeddingCacheKeyVersion, model, dimensions, NormalizeEmbeddingText(text))))
+	return hex.EncodeToString(hash[:])
+}
+
+// NormalizeEmbeddingText lowercases text and collapses runs of whitespace, so
+// trivially different keyword strings share a cache entry.
+func NormalizeEmbeddingText(text string) string {
+	return strings.Join(strings.Fields(strings.ToLower(text)), " ")
+}
+
+// lruCache is a fixed-size, concurrency-safe LRU of embeddings.
+type lruCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	items    map[string]*list.Element
+}
+
+type lruEntry struct {
+	key       string
+	embedding []float32
+}
+
+func newLRUCache(capacity int) *lruCache {
+	return &lruCache{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func (c *lruCache) get(key string) ([]float32, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*lruEntry).embedding, true
+}
+
+func (c *lruCache) put(key string, embedding []float32) {
+	if c.capacity <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		el.Value.(*lruEntry).embedding = embedding
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&lruEntry{key: key, embedding: embedding})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry).key)
+	}
+}
@@ -0,0 +1,97 @@
+package core
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestEmbeddingCacheKey(t *testing.T) {
+	base := EmbeddingCacheKey("m", 8, "rust shanghai")
+	tests := []struct {
+		name  string
+		model string
+		dims  int
+		text  string
+		same  bool
+	}{
+		{"case and spacing", "m", 8, "  Rust\tSHANGHAI ", true},
+		{"other text", "m", 8, "go shanghai", false},
+		{"other model", "m2", 8, "rust shanghai", false},
+		{"other dimensions", "m", 16, "rust shanghai", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := EmbeddingCacheKey(tt.model, tt.dims, tt.text) == base
+			if got != tt.same {
+				t.Errorf("key equal = %v, want %v", got, tt.same)
+			}
+		})
+	}
+}
+
+func TestCachedEmbedderEmbedsNormalizedText(t *testing.T) {
+	inner := &fakeEmbedder{model: "m", dimensions: 4}
+	ce := NewCachedEmbedder(openTestDB(t), inner, 10)
+
+	first, err := ce.GetEmbeddings(context.Background(), []string{"Rust  Developer", "rust developer"})
+	if err != nil {
+		t.Fatalf("GetEmbeddings: %v", err)
+	}
+	if !reflect.DeepEqual(first[0], first[1]) {
+		t.Errorf("spellings of one text got different vectors: %v, %v", first[0], first[1])
+	}
+	if want := [][]string{{"rust developer"}}; !reflect.DeepEqual(inner.calls, want) {
+		t.Fatalf("provider calls = %q, want %q", inner.calls, want)
+	}
+
+	// A later spelling is served from the cache with the same vector.
+	again, err := ce.GetEmbedding(context.Background(), "RUST DEVELOPER")
+	if err != nil {
+		t.Fatalf("GetEmbedding: %v", err)
+	}
+	if !reflect.DeepEqual(again, first[0]) {
+		t.Errorf("cached vector = %v, want %v", again, first[0])
+	}
+	if len(inner.calls) != 1 {
+		t.Errorf("provider called %d times, want 1", len(inner.calls))
+	}
+}
+
+func TestCachedEmbedderSurvivesRestart(t *testing.T) {
+	database := openTestDB(t)
+	inner := &fakeEmbedder{model: "m", dimensions: 4}
+	if _, err := NewCachedEmbedder(database, inner, 10).GetEmbedding(context.Background(), "go"); err != nil {
+		t.Fatalf("GetEmbedding: %v", err)
+	}
+
+	// A new embedder (empty LRU) finds the vector in the database.
+	if _, err := NewCachedEmbedder(database, inner, 10).GetEmbedding(context.Background(), "go"); err != nil {
+		t.Fatalf("GetEmbedding: %v", err)
+	}
+	if len(inner.calls) != 1 {
+		t.Errorf("provider called %d times, want 1", len(inner.calls))
+	}
+}
+
+func TestLRUCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newLRUCache(2)
+	c.put("a", []float32{1})
+	c.put("b", []float32{2})
+	c.get("a")
+	c.put("c", []float32{3})
+
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok := c.get(key); ok != want {
+			t.Errorf("get(%q) present = %v, want %v", key, ok, want)
+		}
+	}
+}
+
+func TestLRUCacheZeroCapacityStoresNothing(t *testing.T) {
+	c := newLRUCache(0)
+	c.put("a", []float32{1})
+	if _, ok := c.get("a"); ok {
+		t.Error("zero-capacity cache returned an entry")
+	}
+}
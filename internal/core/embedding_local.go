@@ -0,0 +1,131 @@
+package core
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// LocalEmbeddingModel is the model name reported for vectors produced by LocalEmbedder.
+const LocalEmbeddingModel = "local-hashed-ngram-v1"
+
+// defaultLocalDimensions is used when no embedding dimensions are configured.
+const defaultLocalDimensions = 256
+
+// LocalEmbedder produces deterministic embeddings without any network access.
+// Each keyword token and its character trigrams are hashed into a fixed number of
+// dimensions (the "hashing trick"), weighted with sublinear term frequency and a
+// length-based IDF approximation, and the result is L2-normalized so that
+// CosineSimilarity behaves the same as with remote embeddings.
+type LocalEmbedder struct {
+	dimensions int
+}
+
+// NewLocalEmbedder creates an offline embedder producing vectors of the given size.
+func NewLocalEmbedder(dimensions int) *LocalEmbedder {
+	if dimensions <= 0 {
+		dimensions = defaultLocalDimensions
+	}
+	return &LocalEmbedder{dimensions: dimensions}
+}
+
+// Model returns the name of the local hashing scheme.
+func (le *LocalEmbedder) Model() string { return LocalEmbeddingModel }
+
+// Dimensions returns the embedding size.
+func (le *LocalEmbedder) Dimensions() int { return le.dimensions }
+
+// Status always reports healthy; there is no remote provider to fail.
+func (le *LocalEmbedder) Status() EmbeddingStatus {
+	return EmbeddingStatus{
+		Provider:   EmbeddingProviderLocal,
+		Mode:       EmbeddingModeSemantic,
+		Model:      LocalEmbeddingModel,
+		Dimensions: le.dimensions,
+		Breaker:    breakerClosed,
+	}
+}
+
+// GetEmbedding returns the hashed n-gram projection of the given text.
+func (le *LocalEmbedder) GetEmbedding(ctx context.Context, text string) ([]float32, error) {
+	vec := make([]float64, le.dimensions)
+
+	counts := make(map[string]int)
+	for _, tok := range tokenize(text) {
+		counts[tok]++
+	}
+
+	for tok, n := range counts {
+		// Sublinear TF, with longer (rarer) tokens weighted slightly higher.
+		tf := 1 + math.Log(float64(n))
+		idf := 1 + math.Log(1+float64(len([]rune(tok))))
+		le.add(vec, "w:"+tok, tf*idf)
+
+		// Character trigrams let related word forms ("engineer", "engineering") overlap.
+		padded := []rune("#" + tok + "#")
+		for i := 0; i+3 <= len(padded); i++ {
+			le.add(vec, "c:"+string(padded[i:i+3]), 0.5*tf)
+		}
+	}
+
+	var norm float64
+	for _, v := range vec {
+		norm += v * v
+	}
+	norm = math.Sqrt(norm)
+
+	embedding := make([]float32, le.dimensions)
+	if norm == 0 {
+		return embedding, nil
+	}
+	for i, v := range vec {
+		embedding[i] = float32(v / norm)
+	}
+	return embedding, nil
+}
+
+// GetEmbeddings embeds each text independently; there is no network round-trip to batch.
+func (le *LocalEmbedder) GetEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		embedding, err := le.GetEmbedding(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		embeddings[i] = embedding
+	}
+	return embeddings, nil
+}
+
+// LookupEmbeddings embeds texts locally, keyed by normalized text; it is as cheap as
+// a cache lookup.
+func (le *LocalEmbedder) LookupEmbeddings(texts []string) map[string][]float32 {
+	vectors := make(map[string][]float32, len(texts))
+	for _, text := range texts {
+		vectors[NormalizeEmbeddingText(text)], _ = le.GetEmbedding(context.Background(), text)
+	}
+	return vectors
+}
+
+// add hashes a feature into one dimension with a hash-derived sign, which keeps
+// collisions from systematically inflating similarity.
+func (le *LocalEmbedder) add(vec []float64, feature string, weight float64) {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(feature))
+	sum := h.Sum64()
+
+	idx := int(sum % uint64(le.dimensions))
+	if (sum>>63)&1 == 1 {
+		weight = -weight
+	}
+	vec[idx] += weight
+}
+
+// tokenize lowercases text and splits it on anything that is not a letter or digit.
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
@@ -0,0 +1,90 @@
+package core
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func localVector(t *testing.T, le *LocalEmbedder, text string) []float32 {
+	t.Helper()
+	vec, err := le.GetEmbedding(context.Background(), text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return vec
+}
+
+func TestLocalEmbedderDeterministic(t *testing.T) {
+	first := localVector(t, NewLocalEmbedder(64), "backend engineer, Go")
+	second := localVector(t, NewLocalEmbedder(64), "backend engineer, Go")
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("component %d differs between calls: %v, %v", i, first[i], second[i])
+		}
+	}
+
+	batch, err := NewLocalEmbedder(64).GetEmbeddings(context.Background(), []string{"backend engineer, Go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if CosineSimilarity(first, batch[0]) < 0.999999 {
+		t.Error("GetEmbeddings differs from GetEmbedding")
+	}
+}
+
+func TestLocalEmbedderNormalized(t *testing.T) {
+	tests := []struct {
+		dimensions int
+		want       int
+	}{
+		{32, 32},
+		{256, 256},
+		{1536, 1536},
+		{0, defaultLocalDimensions},
+		{-1, defaultLocalDimensions},
+	}
+	for _, tt := range tests {
+		le := NewLocalEmbedder(tt.dimensions)
+		if le.Dimensions() != tt.want {
+			t.Errorf("NewLocalEmbedder(%d).Dimensions() = %d, want %d", tt.dimensions, le.Dimensions(), tt.want)
+		}
+		vec := localVector(t, le, "machine learning researcher")
+		if len(vec) != tt.want {
+			t.Errorf("dimensions %d: vector has %d components, want %d", tt.dimensions, len(vec), tt.want)
+		}
+		var norm float64
+		for _, v := range vec {
+			norm += float64(v) * float64(v)
+		}
+		if math.Abs(math.Sqrt(norm)-1) > 1e-5 {
+			t.Errorf("dimensions %d: norm = %v, want 1", tt.dimensions, math.Sqrt(norm))
+		}
+	}
+
+	// Text without letters or digits has no features and stays the zero vector.
+	for _, v := range localVector(t, NewLocalEmbedder(32), " -- ") {
+		if v != 0 {
+			t.Fatal("empty text produced a non-zero vector")
+		}
+	}
+}
+
+func TestLocalEmbedderWordForms(t *testing.T) {
+	le := NewLocalEmbedder(256)
+	tests := []struct {
+		word, related, unrelated string
+	}{
+		{"engineer", "engineering", "cooking"},
+		{"developer", "development", "gardening"},
+		{"design", "designer", "accounting"},
+	}
+	for _, tt := range tests {
+		word := localVector(t, le, tt.word)
+		related := CosineSimilarity(word, localVector(t, le, tt.related))
+		unrelated := CosineSimilarity(word, localVector(t, le, tt.unrelated))
+		if related <= unrelated {
+			t.Errorf("%s: %s scores %.3f, not above %s at %.3f", tt.word, tt.related, related, tt.unrelated, unrelated)
+		}
+	}
+}
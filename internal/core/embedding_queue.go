@@ -0,0 +1,251 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"agentsocial/internal/config"
+)
+
+// Task embedding states reported to agents.
+const (
+	EmbeddingStatusReady   = "ready"   // embedded with the current model; discoverable
+	EmbeddingStatusPending = "pending" // embedding failed; queued for retry
+	EmbeddingStatusStale   = "stale"   // embedded with an old model; being re-embedded
+	EmbeddingStatusNone    = "none"    // not applicable (no keywords, or paused/completed)
+	// EmbeddingStatusKeywordOnly: no embedding provider; found through the keyword index.
+	EmbeddingStatusKeywordOnly = "keyword_only"
+)
+
+// Backoff bounds for the embedding retry queue.
+const (
+	embeddingRetryBaseDelay = 30 * time.Second
+	embeddingRetryMaxDelay  = 1 * time.Hour
+	embeddingRetryBatchSize = 20
+)
+
+// TaskEmbeddingStatus tells an agent whether a task can currently be found by scans.
+type TaskEmbeddingStatus struct {
+	Status        string `json:"status"`
+	Discoverable  bool   `json:"discoverable"`
+	Attempts      int    `json:"attempts,omitempty"`
+	LastError     string `json:"last_error,omitempty"`
+	NextAttemptAt string `json:"next_attempt_at,omitempty"`
+}
+
+// EnqueueTaskEmbeddings records tasks whose embedding could not be computed so
+// the retry worker picks them up. Existing queue entries keep their attempt count.
+func EnqueueTaskEmbeddings(db *sql.DB, taskIDs []string, cause error) {
+	now := time.Now().UTC()
+	for _, taskID := range taskIDs {
+		_, err := db.Exec(
+			`INSERT INTO embedding_queue (task_id, attempts, last_error, next_attempt_at, created_at)
+			 VALUES (?, 0, ?, ?, ?)
+			 ON CONFLICT(task_id) DO UPDATE SET last_error = excluded.last_error`,
+			taskID, cause.Error(), now.Add(embeddingRetryBaseDelay).Format(time.RFC3339), now.Format(time.RFC3339),
+		)
+		if err != nil {
+			log.Printf("WARNING: failed to queue embedding retry for task %s: %v", taskID, err)
+		}
+	}
+}
+
+// DequeueTaskEmbeddings removes tasks from the retry queue, e.g. after a
+// successful embedding or when the task stops participating in matching.
+func DequeueTaskEmbeddings(db *sql.DB, taskIDs []string) {
+	for _, taskID := range taskIDs {
+		_, _ = db.Exec("DELETE FROM embedding_queue WHERE task_id = ?", taskID)
+	}
+}
+
+// GetTaskEmbeddingStatuses returns the embedding status of every task owned by
+// the agent, keyed by internal task ID. In keyword-only mode (no embedding
+// provider) a task is discoverable when it is active and in the keyword index.
+func GetTaskEmbeddingStatuses(db *sql.DB, agentID, model string, dimensions int, keywordOnly bool) (map[string]TaskEmbeddingStatus, error) {
+	rows, err := db.Query(
+		`SELECT t.id, t.status, t.keywords, te.model, length(te.embedding) / 4,
+		        q.attempts, q.last_error, q.next_attempt_at,
+		        EXISTS (SELECT 1 FROM task_fts f WHERE f.task_id = t.id)
+		 FROM tasks t
+		 LEFT JOIN task_embeddings te ON te.task_id = t.id
+		 LEFT JOIN embedding_queue q ON q.task_id = t.id
+		 WHERE t.agent_id = ?`,
+		agentID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query embedding status: %w", err)
+	}
+	defer rows.Close()
+
+	statuses := make(map[string]TaskEmbeddingStatus)
+	for rows.Next() {
+		var (
+			taskID, taskStatus, keywords string
+			embModel                     sql.NullString
+			embDims, attempts            sql.NullInt64
+			lastError, nextAttemptAt     sql.NullString
+			lexical                      bool
+		)
+		if err := rows.Scan(&taskID, &taskStatus, &keywords, &embModel, &embDims, &attempts, &lastError, &nextAttemptAt, &lexical); err != nil {
+			continue
+		}
+
+		if keywordOnly {
+			st := TaskEmbeddingStatus{Status: EmbeddingStatusNone}
+			if lexical && strings.TrimSpace(KeywordsText(keywords)) != "" {
+				st = TaskEmbeddingStatus{Status: EmbeddingStatusKeywordOnly, Discoverable: taskStatus == "active"}
+			}
+			statuses[taskID] = st
+			continue
+		}
+
+		var st TaskEmbeddingStatus
+		switch {
+		case attempts.Valid:
+			st = TaskEmbeddingStatus{
+				Status:        EmbeddingStatusPending,
+				Attempts:      int(attempts.Int64),
+				LastError:     lastError.String,
+				NextAttemptAt: nextAttemptAt.String,
+			}
+		case embModel.Valid && embModel.String == model && int(embDims.Int64) == dimensions:
+			st = TaskEmbeddingStatus{Status: EmbeddingStatusReady}
+		case embModel.Valid:
+			st = TaskEmbeddingStatus{Status: EmbeddingStatusStale}
+		default:
+			st = TaskEmbeddingStatus{Status: EmbeddingStatusNone}
+		}
+		st.Discoverable = st.Status == EmbeddingStatusReady && taskStatus == "active"
+		statuses[taskID] = st
+	}
+
+	return statuses, rows.Err()
+}
+
+// StartEmbeddingRetryWorker periodically retries queued task embeddings with
+// exponential backoff. It also queues matchable tasks that have no embedding
+// at all (e.g. created before the queue existed).
+func StartEmbeddingRetryWorker(db *sql.DB, embClient Embedder, index *VectorIndex, cfg *config.Config) {
+	if cfg.EmbeddingRetryIntervalSeconds <= 0 {
+		return
+	}
+	if _, ok := embClient.(NoopEmbedder); ok {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(cfg.EmbeddingRetryIntervalSeconds) * time.Second)
+	for range ticker.C {
+		queued := queueMissingEmbeddings(db)
+		succeeded, failed := retryQueuedEmbeddings(db, embClient, index, time.Now().UTC())
+		if queued > 0 || succeeded > 0 || failed > 0 {
+			log.Printf("Embedding retry: queued %d missing, embedded %d, %d still failing",
+				queued, succeeded, failed)
+		}
+	}
+}
+
+// queueMissingEmbeddings enqueues active tasks with keywords but no embedding.
+// Returns count queued.
+func queueMissingEmbeddings(db *sql.DB) int64 {
+	now := time.Now().UTC().Format(time.RFC3339)
+	result, err := db.Exec(
+		`INSERT OR IGNORE INTO embedding_queue (task_id, attempts, last_error, next_attempt_at, created_at)
+		 SELECT t.id, 0, 'no embedding found', ?, ?
+		 FROM tasks t
+		 WHERE t.status = 'active'
+		   AND t.keywords NOT IN ('', '[]', 'null')
+		   AND t.id NOT IN (SELECT task_id FROM task_embeddings)`,
+		now, now,
+	)
+	if err != nil {
+		log.Printf("Embedding retry error (queue missing): %v", err)
+		return 0
+	}
+	count, _ := result.RowsAffected()
+	return count
+}
+
+// retryQueuedEmbeddings embeds due queue entries of active tasks in one batch,
+// the same rule the migrator follows. Entries of inactive tasks wait until the
+// agent is back; paused, completed and keyword-less tasks are dropped from the
+// queue. Tasks that become discoverable are queued for the standing match worker.
+// Returns counts of successful and failed tasks.
+func retryQueuedEmbeddings(db *sql.DB, embClient Embedder, index *VectorIndex, now time.Time) (int, int) {
+	rows, err := db.Query(
+		`SELECT q.task_id, q.attempts, t.status, t.keywords
+		 FROM embedding_queue q
+		 JOIN tasks t ON t.id = q.task_id
+		 WHERE q.next_attempt_at <= ? AND t.status != 'inactive'
+		 ORDER BY q.next_attempt_at ASC
+		 LIMIT ?`,
+		now.Format(time.RFC3339), embeddingRetryBatchSize,
+	)
+	if err != nil {
+		log.Printf("Embedding retry error (query queue): %v", err)
+		return 0, 0
+	}
+
+	var (
+		taskIDs, texts, dropped []string
+		attempts                []int
+	)
+	for rows.Next() {
+		var taskID, status, keywords string
+		var n int
+		if err := rows.Scan(&taskID, &n, &status, &keywords); err != nil {
+			continue
+		}
+		text := KeywordsText(keywords)
+		if status != "active" || strings.TrimSpace(text) == "" {
+			dropped = append(dropped, taskID)
+			continue
+		}
+		taskIDs = append(taskIDs, taskID)
+		texts = append(texts, text)
+		attempts = append(attempts, n)
+	}
+	rows.Close()
+
+	// Orphaned entries (task deleted) never match the JOIN above.
+	_, _ = db.Exec("DELETE FROM embedding_queue WHERE task_id NOT IN (SELECT id FROM tasks)")
+	DequeueTaskEmbeddings(db, dropped)
+
+	if len(taskIDs) == 0 {
+		return 0, 0
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	embeddings, err := embClient.GetEmbeddings(ctx, texts)
+	if err != nil {
+		for i, taskID := range taskIDs {
+			delay := min(embeddingRetryBaseDelay<<min(attempts[i], 16), embeddingRetryMaxDelay)
+			_, _ = db.Exec(
+				"UPDATE embedding_queue SET attempts = attempts + 1, last_error = ?, next_attempt_at = ? WHERE task_id = ?",
+				err.Error(), now.Add(delay).Format(time.RFC3339), taskID,
+			)
+		}
+		return 0, len(taskIDs)
+	}
+
+	succeeded := 0
+	for i, embedding := range embeddings {
+		if err := SaveTaskEmbedding(db, index, taskIDs[i], embedding, embClient.Model()); err != nil {
+			log.Printf("Embedding retry error (task %s): %v", taskIDs[i], err)
+			continue
+		}
+		DequeueTaskEmbeddings(db, taskIDs[i:i+1])
+		succeeded++
+
+		QueueStandingMatches(db, taskIDs[i:i+1])
+		// A standing radar can only be matched once it is embedded.
+		QueueStandingBackfills(db, taskIDs[i:i+1])
+	}
+
+	return succeeded, len(taskIDs) - succeeded
+}
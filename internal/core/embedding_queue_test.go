@@ -0,0 +1,245 @@
+package core
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+// queueRow returns a task's embedding queue entry, or ok false if it has none.
+func queueRow(t *testing.T, database *sql.DB, taskID string) (attempts int, lastError, nextAttemptAt string, ok bool) {
+	t.Helper()
+	err := database.QueryRow(
+		"SELECT attempts, last_error, next_attempt_at FROM embedding_queue WHERE task_id = ?", taskID,
+	).Scan(&attempts, &lastError, &nextAttemptAt)
+	if err == sql.ErrNoRows {
+		return 0, "", "", false
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return attempts, lastError, nextAttemptAt, true
+}
+
+func setTaskStatus(t *testing.T, database *sql.DB, taskID, status string) {
+	t.Helper()
+	if _, err := database.Exec("UPDATE tasks SET status = ? WHERE id = ?", status, taskID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRetryQueuedEmbeddingsBackoff(t *testing.T) {
+	database := openTestDB(t)
+	index := NewVectorIndex("m", 2)
+	insertTestAgent(t, database, "agent")
+	insertTestTask(t, database, "task", "agent", "beacon", "hiring", "t", `["go"]`)
+	failing := &fakeEmbedder{model: "m", dimensions: 2, err: errors.New("provider down")}
+
+	EnqueueTaskEmbeddings(database, []string{"task"}, errors.New("timeout"))
+	start := time.Now().UTC()
+
+	if ok, failed := retryQueuedEmbeddings(database, failing, index, start); ok != 0 || failed != 0 || len(failing.calls) != 0 {
+		t.Fatalf("entry retried before it was due: %d, %d, %d calls", ok, failed, len(failing.calls))
+	}
+
+	// Each failure doubles the delay, starting from embeddingRetryBaseDelay.
+	now := start.Add(embeddingRetryBaseDelay + time.Second)
+	for attempt, delay := range []time.Duration{embeddingRetryBaseDelay, 2 * embeddingRetryBaseDelay, 4 * embeddingRetryBaseDelay} {
+		if ok, failed := retryQueuedEmbeddings(database, failing, index, now); ok != 0 || failed != 1 {
+			t.Fatalf("attempt %d: succeeded, failed = %d, %d; want 0, 1", attempt+1, ok, failed)
+		}
+		attempts, lastError, next, queued := queueRow(t, database, "task")
+		want := now.Add(delay).Format(time.RFC3339)
+		if !queued || attempts != attempt+1 || lastError != "provider down" || next != want {
+			t.Fatalf("attempt %d: queue row = %d, %q, %s, %v; want %d, %q, %s",
+				attempt+1, attempts, lastError, next, queued, attempt+1, "provider down", want)
+		}
+		now = now.Add(delay)
+	}
+
+	// The delay is capped.
+	if _, err := database.Exec("UPDATE embedding_queue SET attempts = 30"); err != nil {
+		t.Fatal(err)
+	}
+	retryQueuedEmbeddings(database, failing, index, now)
+	if _, _, next, _ := queueRow(t, database, "task"); next != now.Add(embeddingRetryMaxDelay).Format(time.RFC3339) {
+		t.Errorf("next attempt after many failures = %s, want %s", next, now.Add(embeddingRetryMaxDelay).Format(time.RFC3339))
+	}
+	if index.Len() != 0 {
+		t.Error("failed task was indexed")
+	}
+}
+
+func TestRetryQueuedEmbeddingsSuccess(t *testing.T) {
+	database := openTestDB(t)
+	index := NewVectorIndex("m", 2)
+	insertTestAgent(t, database, "agent")
+	insertTestTask(t, database, "task", "agent", "beacon", "hiring", "t", `["go"]`)
+	fake := &fakeEmbedder{model: "m", dimensions: 2, vectors: map[string][]float32{"go": {1, 0}}}
+
+	EnqueueTaskEmbeddings(database, []string{"task"}, errors.New("timeout"))
+	if ok, failed := retryQueuedEmbeddings(database, fake, index, time.Now().UTC().Add(time.Hour)); ok != 1 || failed != 0 {
+		t.Fatalf("succeeded, failed = %d, %d; want 1, 0", ok, failed)
+	}
+	if _, _, _, queued := queueRow(t, database, "task"); queued {
+		t.Error("task still queued after a successful retry")
+	}
+	if _, ok := index.Vector("task"); !ok {
+		t.Error("task not indexed after a successful retry")
+	}
+	var jobs int
+	if err := database.QueryRow("SELECT COUNT(*) FROM standing_match_queue WHERE task_id = 'task'").Scan(&jobs); err != nil {
+		t.Fatal(err)
+	}
+	if jobs != 2 {
+		t.Errorf("%d standing match jobs queued, want 2", jobs)
+	}
+}
+
+func TestRetryQueuedEmbeddingsTaskStatus(t *testing.T) {
+	database := openTestDB(t)
+	index := NewVectorIndex("m", 2)
+	insertTestAgent(t, database, "agent")
+	tests := []struct {
+		status, keywords string
+		wantQueued       bool
+		wantIndexed      bool
+	}{
+		{"active", `["go"]`, false, true},
+		{"inactive", `["go"]`, true, false},
+		{"paused", `["go"]`, false, false},
+		{"completed", `["go"]`, false, false},
+		{"active", `[]`, false, false},
+	}
+	var ids []string
+	for i, tt := range tests {
+		id := tt.status + "-" + string(rune('a'+i))
+		insertTestTask(t, database, id, "agent", "beacon", "hiring", "t", tt.keywords)
+		setTaskStatus(t, database, id, tt.status)
+		ids = append(ids, id)
+	}
+	EnqueueTaskEmbeddings(database, ids, errors.New("timeout"))
+
+	fake := &fakeEmbedder{model: "m", dimensions: 2}
+	if ok, failed := retryQueuedEmbeddings(database, fake, index, time.Now().UTC().Add(time.Hour)); ok != 1 || failed != 0 {
+		t.Errorf("succeeded, failed = %d, %d; want 1, 0", ok, failed)
+	}
+	for i, tt := range tests {
+		_, _, _, queued := queueRow(t, database, ids[i])
+		_, indexed := index.Vector(ids[i])
+		if queued != tt.wantQueued || indexed != tt.wantIndexed {
+			t.Errorf("%s task with %s: queued, indexed = %v, %v; want %v, %v",
+				tt.status, tt.keywords, queued, indexed, tt.wantQueued, tt.wantIndexed)
+		}
+	}
+
+	// An inactive task is retried once its agent is back.
+	setTaskStatus(t, database, ids[1], "active")
+	if ok, _ := retryQueuedEmbeddings(database, fake, index, time.Now().UTC().Add(time.Hour)); ok != 1 {
+		t.Errorf("reactivated task not retried")
+	}
+}
+
+func TestQueueMissingEmbeddings(t *testing.T) {
+	database := openTestDB(t)
+	index := NewVectorIndex("m", 2)
+	insertTestAgent(t, database, "agent")
+	insertTestTask(t, database, "missing", "agent", "beacon", "hiring", "t", `["go"]`)
+	insertTestTask(t, database, "embedded", "agent", "beacon", "hiring", "t", `["go"]`)
+	insertTestTask(t, database, "no-keywords", "agent", "beacon", "hiring", "t", `[]`)
+	insertTestTask(t, database, "inactive", "agent", "beacon", "hiring", "t", `["go"]`)
+	insertTestTask(t, database, "paused", "agent", "beacon", "hiring", "t", `["go"]`)
+	setTaskStatus(t, database, "inactive", "inactive")
+	setTaskStatus(t, database, "paused", "paused")
+	if err := SaveTaskEmbedding(database, index, "embedded", []float32{1, 0}, "m"); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := queueMissingEmbeddings(database); n != 1 {
+		t.Errorf("queued %d, want 1", n)
+	}
+	for _, id := range []string{"missing", "embedded", "no-keywords", "inactive", "paused"} {
+		_, lastError, _, queued := queueRow(t, database, id)
+		if queued != (id == "missing") {
+			t.Errorf("%s: queued = %v", id, queued)
+		}
+		if queued && lastError != "no embedding found" {
+			t.Errorf("%s: last_error = %q", id, lastError)
+		}
+	}
+	if n := queueMissingEmbeddings(database); n != 0 {
+		t.Errorf("second run queued %d, want 0", n)
+	}
+}
+
+func TestGetTaskEmbeddingStatuses(t *testing.T) {
+	database := openTestDB(t)
+	index := NewVectorIndex("m", 2)
+	insertTestAgent(t, database, "agent")
+	insertTestAgent(t, database, "other")
+	for _, id := range []string{"ready", "paused", "pending", "stale", "resized"} {
+		insertTestTask(t, database, id, "agent", "beacon", "hiring", "t", `["go"]`)
+	}
+	insertTestTask(t, database, "none", "agent", "beacon", "hiring", "t", `[]`)
+	insertTestTask(t, database, "foreign", "other", "beacon", "hiring", "t", `["go"]`)
+	setTaskStatus(t, database, "paused", "paused")
+	for id, model := range map[string]string{"ready": "m", "paused": "m", "stale": "old", "foreign": "m"} {
+		if err := SaveTaskEmbedding(database, index, id, []float32{1, 0}, model); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := SaveTaskEmbedding(database, index, "resized", []float32{1, 0, 0}, "m"); err != nil {
+		t.Fatal(err)
+	}
+	EnqueueTaskEmbeddings(database, []string{"pending"}, errors.New("timeout"))
+	_, _, next, _ := queueRow(t, database, "pending")
+
+	statuses, err := GetTaskEmbeddingStatuses(database, "agent", "m", 2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]TaskEmbeddingStatus{
+		"ready":   {Status: EmbeddingStatusReady, Discoverable: true},
+		"paused":  {Status: EmbeddingStatusReady},
+		"pending": {Status: EmbeddingStatusPending, LastError: "timeout", NextAttemptAt: next},
+		"stale":   {Status: EmbeddingStatusStale},
+		"resized": {Status: EmbeddingStatusStale},
+		"none":    {Status: EmbeddingStatusNone},
+	}
+	if len(statuses) != len(want) {
+		t.Errorf("statuses for %d tasks, want %d: %+v", len(statuses), len(want), statuses)
+	}
+	for id, w := range want {
+		if got := statuses[id]; got != w {
+			t.Errorf("%s: status = %+v, want %+v", id, got, w)
+		}
+	}
+}
+
+func TestGetTaskEmbeddingStatusesKeywordOnly(t *testing.T) {
+	database := openTestDB(t)
+	insertTestAgent(t, database, "agent")
+	insertTestTask(t, database, "active", "agent", "beacon", "hiring", "t", `["go"]`)
+	insertTestTask(t, database, "paused", "agent", "beacon", "hiring", "t", `["go"]`)
+	insertTestTask(t, database, "queued", "agent", "beacon", "hiring", "t", `["go"]`)
+	insertTestTask(t, database, "no-keywords", "agent", "beacon", "hiring", "t", `[]`)
+	setTaskStatus(t, database, "paused", "paused")
+	EnqueueTaskEmbeddings(database, []string{"queued"}, errors.New("timeout"))
+
+	statuses, err := GetTaskEmbeddingStatuses(database, "agent", EmbeddingProviderNone, 0, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]TaskEmbeddingStatus{
+		"active":      {Status: EmbeddingStatusKeywordOnly, Discoverable: true},
+		"paused":      {Status: EmbeddingStatusKeywordOnly},
+		"queued":      {Status: EmbeddingStatusKeywordOnly, Discoverable: true},
+		"no-keywords": {Status: EmbeddingStatusNone},
+	}
+	for id, w := range want {
+		if got := statuses[id]; got != w {
+			t.Errorf("%s: status = %+v, want %+v", id, got, w)
+		}
+	}
+}
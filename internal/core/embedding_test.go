@@ -0,0 +1,188 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// embeddingServer answers with the given status codes in turn, then with vectors
+// of length dims for every input.
+func embeddingServer(t *testing.T, dims int, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1))
+		if n <= len(statuses) {
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+		var req embeddingRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		var resp embeddingResponse
+		for i := len(req.Input) - 1; i >= 0; i-- { // out of order on purpose
+			vec := make([]float64, dims)
+			vec[i%dims] = 1
+			resp.Data = append(resp.Data, struct {
+				Embedding []float64 `json:"embedding"`
+				Index     int       `json:"index"`
+			}{vec, i})
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestEmbeddingClientRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		statuses   []int
+		maxRetries int
+		wantErr    bool
+		wantCalls  int32
+	}{
+		{"success", nil, 2, false, 1},
+		{"retries 5xx", []int{http.StatusServiceUnavailable}, 2, false, 2},
+		{"retries 429", []int{http.StatusTooManyRequests}, 2, false, 2},
+		{"gives up after max retries", []int{500, 500, 500}, 1, true, 2},
+		{"does not retry 4xx", []int{http.StatusBadRequest}, 2, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, calls := embeddingServer(t, 3, tt.statuses...)
+			ec := NewEmbeddingClient("", "m", 3, EmbeddingClientOptions{BaseURL: srv.URL, MaxRetries: tt.maxRetries})
+
+			embeddings, err := ec.GetEmbeddings(context.Background(), []string{"a", "b"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", got, tt.wantCalls)
+			}
+			if err == nil && (embeddings[0][0] != 1 || embeddings[1][1] != 1) {
+				t.Errorf("embeddings not mapped back by index: %v", embeddings)
+			}
+		})
+	}
+}
+
+func TestEmbeddingClientBreakerOpensOnPersistentFailure(t *testing.T) {
+	srv, calls := embeddingServer(t, 3, 500, 500, 500)
+	ec := NewEmbeddingClient("", "m", 3, EmbeddingClientOptions{
+		BaseURL:          srv.URL,
+		BreakerThreshold: 2,
+		BreakerCooldown:  time.Hour,
+	})
+
+	for i := 0; i < 2; i++ {
+		if _, err := ec.GetEmbedding(context.Background(), "a"); err == nil {
+			t.Fatalf("call %d succeeded against a failing server", i)
+		}
+	}
+	if _, err := ec.GetEmbedding(context.Background(), "a"); !errors.Is(err, ErrEmbeddingUnavailable) {
+		t.Fatalf("err = %v, want ErrEmbeddingUnavailable", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("server called %d times, want 2 (open breaker must not call)", got)
+	}
+	if status := ec.Status(); !status.Degraded || status.Breaker != breakerOpen {
+		t.Errorf("status = %+v, want degraded and open", status)
+	}
+}
+
+func TestEmbeddingClientNeedsKeyForPublicAPI(t *testing.T) {
+	ec := NewEmbeddingClient("", "m", 3, EmbeddingClientOptions{})
+	if _, err := ec.GetEmbedding(context.Background(), "a"); !errors.Is(err, ErrEmbeddingNotConfigured) {
+		t.Errorf("err = %v, want ErrEmbeddingNotConfigured", err)
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	if got := backoffDelay(0, 3*time.Second); got != 3*time.Second {
+		t.Errorf("Retry-After not honoured: %v", got)
+	}
+	if got := backoffDelay(0, time.Hour); got != retryMaxDelay {
+		t.Errorf("Retry-After not capped: %v", got)
+	}
+	for attempt := 0; attempt < 8; attempt++ {
+		ceiling := min(retryBaseDelay<<attempt, retryMaxDelay)
+		if got := backoffDelay(attempt, 0); got < ceiling/2 || got > ceiling {
+			t.Errorf("attempt %d: delay %v outside [%v, %v]", attempt, got, ceiling/2, ceiling)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"5", 5 * time.Second},
+		{"-1", 0},
+		{"soon", 0},
+		{time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+
+	future := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(future); got <= 0 || got > time.Minute {
+		t.Errorf("parseRetryAfter(date) = %v", got)
+	}
+}
+
+func TestEmbeddingClientRejectsUnexpectedDimensions(t *testing.T) {
+	// The server ignores the requested size and answers with 5 floats.
+	srv, _ := embeddingServer(t, 5)
+	ec := NewEmbeddingClient("", "m", 3, EmbeddingClientOptions{BaseURL: srv.URL, BreakerThreshold: 1, BreakerCooldown: time.Hour})
+
+	_, err := ec.GetEmbedding(context.Background(), "a")
+	if err == nil || !strings.Contains(err.Error(), "OPENAI_EMBEDDING_DIMENSIONS=5") {
+		t.Fatalf("err = %v, want a dimension mismatch naming the provider's size", err)
+	}
+	// A configuration mistake is not a provider outage.
+	if status := ec.Status(); status.Degraded {
+		t.Errorf("breaker tripped by a dimension mismatch: %+v", status)
+	}
+}
+
+func TestBlendEmbeddings(t *testing.T) {
+	s := float32(1 / math.Sqrt2)
+	tests := []struct {
+		name string
+		a, b []float32
+		want []float32
+	}{
+		{"equal weight", []float32{1, 0}, []float32{0, 1}, []float32{s, s}},
+		{"magnitude does not weigh", []float32{10, 0}, []float32{0, 0.1}, []float32{s, s}},
+		{"same direction", []float32{3, 4}, []float32{6, 8}, []float32{0.6, 0.8}},
+		{"opposite directions cancel", []float32{1, 0}, []float32{-2, 0}, []float32{0, 0}},
+		{"zero vector", []float32{0, 2}, []float32{0, 0}, []float32{0, 1}},
+		{"size mismatch keeps a", []float32{1, 2}, []float32{1, 2, 3}, []float32{1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := BlendEmbeddings(tt.a, tt.b)
+			if len(got) != len(tt.want) {
+				t.Fatalf("BlendEmbeddings = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if math.Abs(float64(got[i]-tt.want[i])) > 1e-6 {
+					t.Fatalf("BlendEmbeddings = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
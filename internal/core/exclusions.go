@@ -0,0 +1,81 @@
+package core
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Contact describes an existing conversation between a task and a counterpart task.
+type Contact struct {
+	ConversationID string
+	State          string
+}
+
+// ContactedTasks returns the counterpart tasks that already share a conversation with
+// the given task, keyed by counterpart task ID. Each conversation row is the pair that
+// ComputeConversationID hashes, so this covers every state, including concluded and
+// expired ones.
+func ContactedTasks(db *sql.DB, taskID string) (map[string]Contact, error) {
+	rows, err := db.Query(`
+		SELECT id, state,
+		       CASE WHEN initiator_task = ? THEN target_task ELSE initiator_task END
+		FROM conversations
+		WHERE initiator_task = ? OR target_task = ?`,
+		taskID, taskID, taskID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query conversations: %w", err)
+	}
+	defer rows.Close()
+
+	contacted := make(map[string]Contact)
+	for rows.Next() {
+		var c Contact
+		var counterpart string
+		if err := rows.Scan(&c.ConversationID, &c.State, &counterpart); err != nil {
+			return nil, fmt.Errorf("failed to scan conversation: %w", err)
+		}
+		contacted[counterpart] = c
+	}
+	return contacted, rows.Err()
+}
+
+// BlockedAgents returns the agents the given agent has blocked or been blocked by.
+// Blocks are honoured in both directions so a blocked agent cannot keep finding
+// the agent who blocked them.
+func BlockedAgents(db *sql.DB, agentID string) ([]string, error) {
+	rows, err := db.Query(`
+		SELECT blocked_id FROM agent_blocks WHERE blocker_id = ?
+		UNION
+		SELECT blocker_id FROM agent_blocks WHERE blocked_id = ?`,
+		agentID, agentID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query blocks: %w", err)
+	}
+	defer rows.Close()
+
+	var blocked []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan block: %w", err)
+		}
+		blocked = append(blocked, id)
+	}
+	return blocked, rows.Err()
+}
+
+// AgentsBlocked reports whether either agent has blocked the other.
+func AgentsBlocked(db *sql.DB, agentID, otherID string) (bool, error) {
+	var n int
+	err := db.QueryRow(
+		`SELECT COUNT(*) FROM agent_blocks
+		 WHERE (blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)`,
+		agentID, otherID, otherID, agentID,
+	).Scan(&n)
+	if err != nil {
+		return false, fmt.Errorf("failed to query blocks: %w", err)
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,150 @@
+package core
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func insertTestBlock(t *testing.T, database *sql.DB, blocker, blocked string) {
+	t.Helper()
+	_, err := database.Exec(
+		"INSERT INTO agent_blocks (blocker_id, blocked_id, created_at) VALUES (?, ?, ?)",
+		blocker, blocked, time.Now().UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFindMatchesExcludesBlockedAgents(t *testing.T) {
+	database := openTestDB(t)
+	index := NewVectorIndex("m", 2)
+	for _, id := range []string{"me", "blocked-by-me", "blocker", "stranger"} {
+		insertTestAgent(t, database, id)
+	}
+	addTestBeacon(t, database, index, "b-blocked", "blocked-by-me", `["go"]`, []float32{1, 0})
+	addTestBeacon(t, database, index, "b-blocker", "blocker", `["go"]`, []float32{1, 0})
+	addTestBeacon(t, database, index, "b-stranger", "stranger", `["go"]`, []float32{1, 0})
+	insertTestBlock(t, database, "me", "blocked-by-me")
+	insertTestBlock(t, database, "blocker", "me")
+
+	tests := []struct {
+		name      string
+		embedding []float32
+	}{
+		{"hybrid", []float32{1, 0}},
+		{"keyword-only", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocked, err := BlockedAgents(database, "me")
+			if err != nil {
+				t.Fatal(err)
+			}
+			results, _, err := FindMatches(database, index, tt.embedding, MatchOptions{
+				Mode:            "beacon",
+				MaxResults:      10,
+				ExcludeAgentIDs: append(blocked, "me"),
+				Keywords:        []string{"go"},
+				LexicalWeight:   0.3,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := resultIDs(results); !equalIDs(got, []string{"b-stranger"}) {
+				t.Errorf("results = %v, want [b-stranger]", got)
+			}
+		})
+	}
+}
+
+func TestAgentsBlocked(t *testing.T) {
+	database := openTestDB(t)
+	for _, id := range []string{"a", "b", "c"} {
+		insertTestAgent(t, database, id)
+	}
+	insertTestBlock(t, database, "a", "b")
+
+	tests := []struct {
+		agent, other string
+		want         bool
+	}{
+		{"a", "b", true},
+		{"b", "a", true},
+		{"a", "c", false},
+		{"c", "b", false},
+	}
+	for _, tt := range tests {
+		if got, err := AgentsBlocked(database, tt.agent, tt.other); err != nil || got != tt.want {
+			t.Errorf("AgentsBlocked(%s, %s) = %v, %v; want %v", tt.agent, tt.other, got, err, tt.want)
+		}
+	}
+}
+
+func TestFindMatchesContactedTasks(t *testing.T) {
+	database := openTestDB(t)
+	index := NewVectorIndex("m", 2)
+	insertTestParticipants(t, database)
+	if err := SaveTaskEmbedding(database, index, "offer", []float32{1, 0}, "m"); err != nil {
+		t.Fatal(err)
+	}
+	insertTestAgent(t, database, "other")
+	addTestBeacon(t, database, index, "fresh", "other", `[]`, []float32{1, 0})
+	insertTestConversation(t, database, "conv", StateConcludedNoMatch)
+
+	tests := []struct {
+		name             string
+		includeContacted bool
+		want             []string
+	}{
+		{"contacted tasks left out", false, []string{"fresh"}},
+		{"contacted tasks included on request", true, []string{"fresh", "offer"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contacted, err := ContactedTasks(database, "ask")
+			if err != nil {
+				t.Fatal(err)
+			}
+			results, _, err := FindMatches(database, index, []float32{1, 0}, MatchOptions{
+				Mode:             "beacon",
+				MaxResults:       10,
+				ExcludeAgentIDs:  []string{"initiator"},
+				Contacted:        contacted,
+				IncludeContacted: tt.includeContacted,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := sortedIDs(results); !equalIDs(got, tt.want) {
+				t.Fatalf("results = %v, want %v", got, tt.want)
+			}
+			for _, m := range results {
+				want := Contact{}
+				if m.TaskID == "offer" {
+					want = Contact{ConversationID: "conv", State: StateConcludedNoMatch}
+				}
+				if m.ConversationID != want.ConversationID || m.ConversationState != want.State {
+					t.Errorf("%s: conversation = %q, %q; want %+v", m.TaskID, m.ConversationID, m.ConversationState, want)
+				}
+			}
+		})
+	}
+}
+
+func TestContactedTasksBothDirections(t *testing.T) {
+	database := openTestDB(t)
+	insertTestParticipants(t, database)
+	insertTestConversation(t, database, "conv", StateActive)
+
+	for task, counterpart := range map[string]string{"ask": "offer", "offer": "ask"} {
+		contacted, err := ContactedTasks(database, task)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c, ok := contacted[counterpart]; !ok || len(contacted) != 1 || c.ConversationID != "conv" || c.State != StateActive {
+			t.Errorf("ContactedTasks(%s) = %+v, want only %s in conv", task, contacted, counterpart)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package core
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Explanations list at most maxExplanationPairs keyword pairs, and only non-identical
+// pairs at least minExplanationSimilarity apart so unrelated keywords are not listed.
+const (
+	maxExplanationPairs      = 5
+	minExplanationSimilarity = 0.25
+)
+
+// KeywordPair links a query keyword to the closest keyword of the matched task.
+type KeywordPair struct {
+	Keyword    string  `json:"keyword"`
+	Matched    string  `json:"matched"`
+	Similarity float64 `json:"similarity"`
+	Exact      bool    `json:"exact"`
+}
+
+// MatchBoost is a ranking signal that raised a match's score.
+type MatchBoost struct {
+	Name  string  `json:"name"`
+	Value float64 `json:"value"`
+}
+
+// MatchExplanation tells an agent why a task matched, so it can give the user
+// (and the counterpart) concrete context.
+type MatchExplanation struct {
+	Summary      string        `json:"summary"`
+	KeywordPairs []KeywordPair `json:"keyword_pairs"`
+	TypeRule     string        `json:"type_rule,omitempty"`
+	Boosts       []MatchBoost  `json:"boosts,omitempty"`
+}
+
+// ExplainMatches attaches an explanation to each result. For every query keyword it
+// finds the matched task's closest stored keyword: identical keywords score 1,
+// keywords sharing words by the share of words they have in common, and others by
+// cosine similarity of keyword vectors the embedder already has at hand (see
+// KeywordVectorSource). Explanations never call the embedding provider, so they add
+// no latency or cost to a scan, and never fail it.
+func ExplainMatches(db *sql.DB, embClient Embedder, queryKeywords []string, lexicalWeight float64, results []MatchResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+
+	counterpart, err := loadTaskKeywords(db, results)
+	if err != nil {
+		return err
+	}
+
+	vectors := keywordVectors(embClient, queryKeywords, counterpart)
+
+	for i := range results {
+		m := &results[i]
+		pairs := closestKeywordPairs(queryKeywords, counterpart[m.TaskID], vectors)
+
+		var boosts []MatchBoost
+		if m.LexicalScore > 0 && lexicalWeight > 0 {
+			boosts = append(boosts, MatchBoost{Name: "keyword_match", Value: m.LexicalScore})
+		}
+		if m.OutcomeBoost != 0 && m.OutcomeBoost != 1 {
+			boosts = append(boosts, MatchBoost{Name: "outcome_history", Value: m.OutcomeBoost})
+		}
+
+		m.Explanation = &MatchExplanation{
+			Summary:      explanationSummary(pairs, m.TypeRule),
+			KeywordPairs: pairs,
+			TypeRule:     m.TypeRule,
+			Boosts:       boosts,
+		}
+	}
+
+	return nil
+}
+
+// loadTaskKeywords returns the stored keywords of each result's task.
+func loadTaskKeywords(db *sql.DB, results []MatchResult) (map[string][]string, error) {
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(results)), ",")
+	args := make([]interface{}, len(results))
+	for i, m := range results {
+		args[i] = m.TaskID
+	}
+
+	rows, err := db.Query("SELECT id, keywords FROM tasks WHERE id IN ("+placeholders+")", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query task keywords: %w", err)
+	}
+	defer rows.Close()
+
+	keywords := make(map[string][]string, len(results))
+	for rows.Next() {
+		var id, keywordsJSON string
+		if err := rows.Scan(&id, &keywordsJSON); err != nil {
+			return nil, fmt.Errorf("failed to scan task keywords: %w", err)
+		}
+		var kw []string
+		_ = json.Unmarshal([]byte(keywordsJSON), &kw)
+		keywords[id] = kw
+	}
+	return keywords, rows.Err()
+}
+
+// KeywordVectorSource is implemented by embedders that can return keyword vectors
+// without a provider round-trip. Results are keyed by normalized text; texts it
+// cannot answer for are left out.
+type KeywordVectorSource interface {
+	LookupEmbeddings(texts []string) map[string][]float32
+}
+
+// keywordVectors looks up every distinct keyword on both sides, keyed by normalized
+// text. Returns nil if embClient cannot answer without calling its provider.
+func keywordVectors(embClient Embedder, queryKeywords []string, counterpart map[string][]string) map[string][]float32 {
+	source, ok := embClient.(KeywordVectorSource)
+	if !ok {
+		return nil
+	}
+
+	seen := make(map[string]struct{})
+	var texts []string
+	add := func(kw string) {
+		key := NormalizeEmbeddingText(kw)
+		if key == "" {
+			return
+		}
+		if _, ok := seen[key]; !ok {
+			seen[key] = struct{}{}
+			texts = append(texts, key)
+		}
+	}
+	for _, kw := range queryKeywords {
+		add(kw)
+	}
+	for _, kws := range counterpart {
+		for _, kw := range kws {
+			add(kw)
+		}
+	}
+	if len(texts) == 0 {
+		return nil
+	}
+	return source.LookupEmbeddings(texts)
+}
+
+// closestKeywordPairs pairs each query keyword with its closest counterpart keyword,
+// best pairs first. vectors may be nil or partial; keywords without both vectors are
+// compared by shared words only.
+func closestKeywordPairs(queryKeywords, counterpart []string, vectors map[string][]float32) []KeywordPair {
+	pairs := []KeywordPair{}
+	for _, q := range queryKeywords {
+		qKey := NormalizeEmbeddingText(q)
+		if qKey == "" {
+			continue
+		}
+
+		var best KeywordPair
+		found := false
+		for _, kw := range counterpart {
+			key := NormalizeEmbeddingText(kw)
+			if key == "" {
+				continue
+			}
+			if key == qKey {
+				best, found = KeywordPair{Keyword: q, Matched: kw, Similarity: 1, Exact: true}, true
+				break
+			}
+			sim := wordOverlap(qKey, key)
+			if qVec, ok := vectors[qKey]; ok {
+				if vec, ok := vectors[key]; ok {
+					sim = math.Max(sim, CosineSimilarity(qVec, vec))
+				}
+			}
+			if !found || sim > best.Similarity {
+				best, found = KeywordPair{Keyword: q, Matched: kw, Similarity: sim}, true
+			}
+		}
+		if found && (best.Exact || best.Similarity >= minExplanationSimilarity) {
+			pairs = append(pairs, best)
+		}
+	}
+
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i].Similarity > pairs[j].Similarity
+	})
+	if len(pairs) > maxExplanationPairs {
+		pairs = pairs[:maxExplanationPairs]
+	}
+	return pairs
+}
+
+// wordOverlap is the Jaccard similarity of two keywords' words, so "backend
+// engineer" and "senior engineer" are related without any embedding.
+func wordOverlap(a, b string) float64 {
+	words := make(map[string]bool)
+	for _, w := range tokenize(a) {
+		words[w] = true
+	}
+	union := len(words)
+	shared := 0
+	for _, w := range tokenize(b) {
+		seen, ok := words[w]
+		switch {
+		case !ok:
+			words[w] = false
+			union++
+		case seen:
+			words[w] = false // count repeated words once
+			shared++
+		}
+	}
+	if union == 0 {
+		return 0
+	}
+	return float64(shared) / float64(union)
+}
+
+// explanationSummary renders a one-line, human-readable explanation.
+func explanationSummary(pairs []KeywordPair, typeRule string) string {
+	var shared, near []string
+	for _, p := range pairs {
+		if p.Exact {
+			shared = append(shared, p.Keyword)
+		} else {
+			near = append(near, fmt.Sprintf("%s ~ %s (%.2f)", p.Keyword, p.Matched, p.Similarity))
+		}
+	}
+
+	var parts []string
+	if len(shared) > 0 {
+		parts = append(parts, "Shared keywords: "+strings.Join(shared, ", "))
+	}
+	if len(near) > 0 {
+		parts = append(parts, "Closest keywords: "+strings.Join(near, "; "))
+	}
+	if typeRule != "" {
+		parts = append(parts, "Task types "+typeRule)
+	}
+	if len(parts) == 0 {
+		return "Overall keyword similarity"
+	}
+	return strings.Join(parts, ". ") + "."
+}
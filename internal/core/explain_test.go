@@ -0,0 +1,177 @@
+package core
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestWordOverlap(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"backend engineer", "senior engineer", 1.0 / 3},
+		{"go", "go", 1},
+		{"go go", "go", 1},
+		{"python", "java", 0},
+		{"", "go", 0},
+		{"Machine-Learning", "machine learning", 1},
+	}
+	for _, tt := range tests {
+		if got := wordOverlap(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("wordOverlap(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestClosestKeywordPairs(t *testing.T) {
+	vectors := map[string][]float32{
+		"backend":     {1, 0},
+		"server-side": unitAt(0.7),
+		"cooking":     {0, 1},
+	}
+
+	tests := []struct {
+		name        string
+		query       []string
+		counterpart []string
+		vectors     map[string][]float32
+		want        []KeywordPair
+	}{
+		{
+			"exact match ignores case and spacing",
+			[]string{"Python"}, []string{"java", " python "}, nil,
+			[]KeywordPair{{Keyword: "Python", Matched: " python ", Similarity: 1, Exact: true}},
+		},
+		{
+			"shared words without vectors",
+			[]string{"backend engineer"}, []string{"senior engineer", "cooking"}, nil,
+			[]KeywordPair{{Keyword: "backend engineer", Matched: "senior engineer", Similarity: 1.0 / 3}},
+		},
+		{
+			"vector similarity",
+			[]string{"backend"}, []string{"cooking", "server-side"}, vectors,
+			[]KeywordPair{{Keyword: "backend", Matched: "server-side", Similarity: 0.7}},
+		},
+		{
+			"unrelated keywords are left out",
+			[]string{"backend"}, []string{"cooking"}, vectors,
+			[]KeywordPair{},
+		},
+		{
+			"missing vector falls back to shared words",
+			[]string{"backend"}, []string{"server-side"}, map[string][]float32{"backend": {1, 0}},
+			[]KeywordPair{},
+		},
+		{
+			"best pairs first",
+			[]string{"backend", "go"}, []string{"server-side", "go"}, vectors,
+			[]KeywordPair{
+				{Keyword: "go", Matched: "go", Similarity: 1, Exact: true},
+				{Keyword: "backend", Matched: "server-side", Similarity: 0.7},
+			},
+		},
+		{
+			"capped",
+			[]string{"a", "b", "c", "d", "e", "f"}, []string{"a", "b", "c", "d", "e", "f"}, nil,
+			[]KeywordPair{
+				{Keyword: "a", Matched: "a", Similarity: 1, Exact: true},
+				{Keyword: "b", Matched: "b", Similarity: 1, Exact: true},
+				{Keyword: "c", Matched: "c", Similarity: 1, Exact: true},
+				{Keyword: "d", Matched: "d", Similarity: 1, Exact: true},
+				{Keyword: "e", Matched: "e", Similarity: 1, Exact: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := closestKeywordPairs(tt.query, tt.counterpart, tt.vectors)
+			if len(got) != len(tt.want) {
+				t.Fatalf("pairs = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				w := tt.want[i]
+				if got[i].Keyword != w.Keyword || got[i].Matched != w.Matched || got[i].Exact != w.Exact ||
+					math.Abs(got[i].Similarity-w.Similarity) > 1e-6 {
+					t.Errorf("pair %d = %+v, want %+v", i, got[i], w)
+				}
+			}
+		})
+	}
+}
+
+func TestExplanationSummary(t *testing.T) {
+	tests := []struct {
+		name     string
+		pairs    []KeywordPair
+		typeRule string
+		want     string
+	}{
+		{"nothing to show", nil, "", "Overall keyword similarity"},
+		{"type rule only", nil, "hiring/job-seeking", "Task types hiring/job-seeking."},
+		{
+			"shared and closest",
+			[]KeywordPair{
+				{Keyword: "Python", Matched: "python", Similarity: 1, Exact: true},
+				{Keyword: "go", Matched: "go", Similarity: 1, Exact: true},
+				{Keyword: "backend", Matched: "server-side", Similarity: 0.714},
+			},
+			"hiring/job-seeking",
+			"Shared keywords: Python, go. Closest keywords: backend ~ server-side (0.71). Task types hiring/job-seeking.",
+		},
+		{
+			"closest only",
+			[]KeywordPair{{Keyword: "a", Matched: "b", Similarity: 0.5}, {Keyword: "c", Matched: "d", Similarity: 0.3}},
+			"",
+			"Closest keywords: a ~ b (0.50); c ~ d (0.30).",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := explanationSummary(tt.pairs, tt.typeRule); got != tt.want {
+				t.Errorf("summary = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExplainMatchesNeverCallsProvider(t *testing.T) {
+	database := openTestDB(t)
+	insertTestAgent(t, database, "agent")
+	insertTestTask(t, database, "task", "agent", "beacon", "hiring", "t", `["Go", "server-side"]`)
+
+	fake := &fakeEmbedder{model: "m", dimensions: 2, vectors: map[string][]float32{
+		"backend":     {1, 0},
+		"server-side": unitAt(0.7),
+	}}
+	cached := NewCachedEmbedder(database, fake, 10)
+	// Only "backend" has been embedded before; "server-side" is not in the cache.
+	if _, err := cached.GetEmbeddings(context.Background(), []string{"backend"}); err != nil {
+		t.Fatal(err)
+	}
+	fake.calls = nil
+
+	tests := []struct {
+		name      string
+		embClient Embedder
+	}{
+		{"cached provider", cached},
+		{"no provider", NoopEmbedder{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := []MatchResult{{TaskID: "task", TypeRule: "hiring/job-seeking"}}
+			if err := ExplainMatches(database, tt.embClient, []string{"go", "backend"}, 0.3, results); err != nil {
+				t.Fatal(err)
+			}
+			if len(fake.calls) != 0 {
+				t.Errorf("provider called with %q", fake.calls)
+			}
+			want := "Shared keywords: go. Task types hiring/job-seeking."
+			if got := results[0].Explanation.Summary; got != want {
+				t.Errorf("summary = %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package core
+
+import (
+	"database/sql"
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// earthRadiusKm is the mean Earth radius used by HaversineKm.
+const earthRadiusKm = 6371.0
+
+// GeoPoint is a task's location. RadiusKm > 0 limits matches to counterparts
+// within that distance; 0 means the location is informational only.
+type GeoPoint struct {
+	Lat      float64
+	Lon      float64
+	RadiusKm float64
+}
+
+// HaversineKm returns the great-circle distance between two points in kilometres.
+func HaversineKm(a, b GeoPoint) float64 {
+	lat1, lat2 := a.Lat*math.Pi/180, b.Lat*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b.Lon - a.Lon) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// geoMatch applies location constraints between a query task and a candidate.
+// When both are located, each side's radius (if set) must cover the distance.
+// A query radius excludes unlocated candidates; an unlocated query is not
+// constrained. Returns the distance when both are located.
+func geoMatch(origin, candidate *GeoPoint) (distanceKm float64, located, ok bool) {
+	if origin == nil {
+		return 0, false, true
+	}
+	if candidate == nil {
+		return 0, false, origin.RadiusKm <= 0
+	}
+
+	d := HaversineKm(*origin, *candidate)
+	if origin.RadiusKm > 0 && d > origin.RadiusKm {
+		return d, true, false
+	}
+	if candidate.RadiusKm > 0 && d > candidate.RadiusKm {
+		return d, true, false
+	}
+	return d, true, true
+}
+
+// gazetteerCSV is a small offline list of major cities: name, aliases separated
+// by "|", latitude, longitude.
+//
+//go:embed gazetteer.csv
+var gazetteerCSV string
+
+var (
+	gazetteerOnce   sync.Once
+	gazetteerPlaces map[string]GeoPoint
+)
+
+// ResolvePlace looks a place name up in the bundled gazetteer. Matching is
+// case-insensitive on the city name or any alias; anything after the first comma
+// ("Shanghai, China") is ignored.
+func ResolvePlace(name string) (GeoPoint, bool) {
+	gazetteerOnce.Do(loadGazetteer)
+
+	name, _, _ = strings.Cut(name, ",")
+	p, ok := gazetteerPlaces[normalizePlace(name)]
+	return p, ok
+}
+
+func loadGazetteer() {
+	gazetteerPlaces = make(map[string]GeoPoint)
+
+	r := csv.NewReader(strings.NewReader(gazetteerCSV))
+	r.Comment = '#'
+	records, err := r.ReadAll()
+	if err != nil {
+		panic(fmt.Sprintf("invalid embedded gazetteer: %v", err))
+	}
+
+	for _, rec := range records {
+		lat, errLat := strconv.ParseFloat(rec[2], 64)
+		lon, errLon := strconv.ParseFloat(rec[3], 64)
+		if errLat != nil || errLon != nil {
+			panic(fmt.Sprintf("invalid embedded gazetteer coordinates for %q", rec[0]))
+		}
+		p := GeoPoint{Lat: lat, Lon: lon}
+		gazetteerPlaces[normalizePlace(rec[0])] = p
+		for _, alias := range strings.Split(rec[1], "|") {
+			if alias != "" {
+				gazetteerPlaces[normalizePlace(alias)] = p
+			}
+		}
+	}
+}
+
+func normalizePlace(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// taskGeoPoint builds a task's location from its stored columns, or nil if it has none.
+func taskGeoPoint(lat, lon sql.NullFloat64, radiusKm float64) *GeoPoint {
+	if !lat.Valid || !lon.Valid {
+		return nil
+	}
+	return &GeoPoint{Lat: lat.Float64, Lon: lon.Float64, RadiusKm: radiusKm}
+}
+
+// TaskLocation returns a task's location, or nil if it has none.
+func TaskLocation(db *sql.DB, taskID string) (*GeoPoint, error) {
+	var lat, lon sql.NullFloat64
+	var radius float64
+	err := db.QueryRow("SELECT latitude, longitude, radius_km FROM tasks WHERE id = ?", taskID).Scan(&lat, &lon, &radius)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up task location: %w", err)
+	}
+	return taskGeoPoint(lat, lon, radius), nil
+}
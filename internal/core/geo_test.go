@@ -0,0 +1,152 @@
+package core
+
+import (
+	"math"
+	"testing"
+)
+
+var (
+	shanghai = GeoPoint{Lat: 31.2304, Lon: 121.4737}
+	suzhou   = GeoPoint{Lat: 31.2990, Lon: 120.5853}
+	beijing  = GeoPoint{Lat: 39.9042, Lon: 116.4074}
+)
+
+func TestHaversineKm(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b GeoPoint
+		want float64
+	}{
+		{"same point", shanghai, shanghai, 0},
+		{"Shanghai-Beijing", shanghai, beijing, 1067},
+		{"Shanghai-Suzhou", shanghai, suzhou, 85},
+		{"quarter meridian", GeoPoint{Lat: 0, Lon: 0}, GeoPoint{Lat: 90, Lon: 0}, math.Pi / 2 * earthRadiusKm},
+		{"antipodes", GeoPoint{Lat: 0, Lon: 0}, GeoPoint{Lat: 0, Lon: 180}, math.Pi * earthRadiusKm},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := HaversineKm(tt.a, tt.b)
+			if math.Abs(got-tt.want) > 1 {
+				t.Errorf("HaversineKm = %.1f, want %.1f", got, tt.want)
+			}
+			if back := HaversineKm(tt.b, tt.a); math.Abs(back-got) > 1e-9 {
+				t.Errorf("not symmetric: %v vs %v", got, back)
+			}
+		})
+	}
+}
+
+func TestGeoMatch(t *testing.T) {
+	within := func(p GeoPoint, km float64) *GeoPoint {
+		p.RadiusKm = km
+		return &p
+	}
+
+	tests := []struct {
+		name        string
+		origin      *GeoPoint
+		candidate   *GeoPoint
+		wantLocated bool
+		wantOK      bool
+	}{
+		{"no origin", nil, &beijing, false, true},
+		{"unlocated candidate, no radius", &shanghai, nil, false, true},
+		{"unlocated candidate, radius", within(shanghai, 100), nil, false, false},
+		{"inside origin radius", within(shanghai, 100), &suzhou, true, true},
+		{"outside origin radius", within(shanghai, 100), &beijing, true, false},
+		{"outside candidate radius", &shanghai, within(beijing, 100), true, false},
+		{"both radii cover", within(shanghai, 2000), within(beijing, 2000), true, true},
+		{"informational only", &shanghai, &beijing, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, located, ok := geoMatch(tt.origin, tt.candidate)
+			if located != tt.wantLocated || ok != tt.wantOK {
+				t.Errorf("geoMatch located, ok = %v, %v; want %v, %v", located, ok, tt.wantLocated, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestResolvePlace(t *testing.T) {
+	tests := []struct {
+		name string
+		want *GeoPoint
+	}{
+		{"Shanghai", &shanghai},
+		{"  shanghai ", &shanghai},
+		{"上海", &shanghai},
+		{"Shanghai, China", &shanghai},
+		{"Peking", &beijing},
+		{"new  york city", &GeoPoint{Lat: 40.7128, Lon: -74.0060}},
+		{"Atlantis", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ResolvePlace(tt.name)
+			if ok != (tt.want != nil) {
+				t.Fatalf("ResolvePlace(%q) found = %v, want %v", tt.name, ok, tt.want != nil)
+			}
+			if ok && got != *tt.want {
+				t.Errorf("ResolvePlace(%q) = %+v, want %+v", tt.name, got, *tt.want)
+			}
+		})
+	}
+}
+
+func TestFindMatchesGeoFromIndex(t *testing.T) {
+	database := openTestDB(t)
+	index := NewVectorIndex("m", 2)
+	insertTestAgent(t, database, "agent")
+	place := func(id string, p GeoPoint) {
+		t.Helper()
+		if _, err := database.Exec("UPDATE tasks SET latitude = ?, longitude = ?, radius_km = ? WHERE id = ?", p.Lat, p.Lon, p.RadiusKm, id); err != nil {
+			t.Fatal(err)
+		}
+		if err := index.Refresh(database, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	addTestBeacon(t, database, index, "suzhou", "agent", `["go"]`, unitAt(0.9))
+	addTestBeacon(t, database, index, "beijing", "agent", `["go"]`, unitAt(0.9))
+	addTestBeacon(t, database, index, "nowhere", "agent", `["go"]`, unitAt(0.9))
+	addTestBeacon(t, database, index, "suzhou-unembedded", "agent", `["go"]`, nil)
+	place("suzhou", suzhou)
+	place("beijing", beijing)
+	place("suzhou-unembedded", suzhou)
+
+	find := func(embedding []float32) []MatchResult {
+		t.Helper()
+		origin := shanghai
+		origin.RadiusKm = 100
+		results, _, err := FindMatches(database, index, embedding, MatchOptions{
+			Mode:       "beacon",
+			MaxResults: 10,
+			MinScore:   0.5,
+			Keywords:   []string{"go"},
+			Origin:     &origin,
+		})
+		if err != nil {
+			t.Fatalf("FindMatches: %v", err)
+		}
+		return results
+	}
+
+	results := find([]float32{1, 0})
+	if got := sortedIDs(results); !equalIDs(got, []string{"suzhou"}) {
+		t.Fatalf("results = %v, want [suzhou]", got)
+	}
+	if d := results[0].DistanceKm; d == nil || math.Abs(*d-85) > 1 {
+		t.Errorf("DistanceKm = %v, want about 85", d)
+	}
+	if got := sortedIDs(find(nil)); !equalIDs(got, []string{"suzhou", "suzhou-unembedded"}) {
+		t.Errorf("keyword-only results = %v", got)
+	}
+
+	// A moved task is matched at its new location once refreshed.
+	place("beijing", GeoPoint{Lat: 31.2, Lon: 121.5})
+	if got := sortedIDs(find([]float32{1, 0})); !equalIDs(got, []string{"beijing", "suzhou"}) {
+		t.Errorf("after move results = %v", got)
+	}
+}
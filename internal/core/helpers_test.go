@@ -0,0 +1,125 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"agentsocial/internal/db"
+)
+
+// openTestDB returns a migrated database in a temporary directory.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	database, err := db.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	return database
+}
+
+// insertTestAgent creates an active agent that heartbeated just now.
+func insertTestAgent(t *testing.T, database *sql.DB, id string) {
+	t.Helper()
+	now := time.Now().UTC().Format(time.RFC3339)
+	_, err := database.Exec(
+		`INSERT INTO agents (id, agent_token, display_name, ip_address, mac_address, status, last_heartbeat, created_at)
+		 VALUES (?, ?, ?, '', '', 'active', ?, ?)`,
+		id, "token-"+id, id, now, now,
+	)
+	if err != nil {
+		t.Fatalf("insert agent %s: %v", id, err)
+	}
+}
+
+// insertTestTask creates an active task; its internal ID and task_id are both id.
+func insertTestTask(t *testing.T, database *sql.DB, id, agentID, mode, taskType, title, keywordsJSON string) {
+	t.Helper()
+	now := time.Now().UTC().Format(time.RFC3339)
+	_, err := database.Exec(
+		`INSERT INTO tasks (id, agent_id, task_id, mode, type, title, keywords, status, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, 'active', ?, ?)`,
+		id, agentID, id, mode, taskType, title, keywordsJSON, now, now,
+	)
+	if err != nil {
+		t.Fatalf("insert task %s: %v", id, err)
+	}
+}
+
+// insertTestParticipants creates the agents "initiator" and "target" with the tasks
+// "ask" (a job-seeking radar) and "offer" (a hiring beacon) that test conversations use.
+func insertTestParticipants(t *testing.T, database *sql.DB) {
+	t.Helper()
+	insertTestAgent(t, database, "initiator")
+	insertTestAgent(t, database, "target")
+	insertTestTask(t, database, "ask", "initiator", "radar", "job-seeking", "ask", `[]`)
+	insertTestTask(t, database, "offer", "target", "beacon", "hiring", "offer", `[]`)
+}
+
+// insertTestConversation opens a conversation from the initiator's task "ask" to the
+// target's task "offer" in the given state, and records events as its history.
+func insertTestConversation(t *testing.T, database *sql.DB, id, state string, events ...[3]string) {
+	t.Helper()
+	now := time.Now().UTC().Format(time.RFC3339)
+	_, err := database.Exec(
+		`INSERT INTO conversations (id, initiator_agent, target_agent, initiator_task, target_task, state, created_at, updated_at)
+		 VALUES (?, 'initiator', 'target', 'ask', 'offer', ?, ?, ?)`,
+		id, state, now, now,
+	)
+	if err != nil {
+		t.Fatalf("insert conversation %s: %v", id, err)
+	}
+	for _, e := range events { // event, from state, actor
+		if err := recordConversationTransition(database, id, e[0], e[1], "", 1, 1, e[2], "", now); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// fakeEmbedder returns fixed vectors by text and records what it was asked to embed.
+// When err is set, every call fails with it.
+type fakeEmbedder struct {
+	mu         sync.Mutex
+	model      string
+	dimensions int
+	vectors    map[string][]float32
+	err        error
+	calls      [][]string
+}
+
+func (f *fakeEmbedder) GetEmbedding(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := f.GetEmbeddings(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+func (f *fakeEmbedder) GetEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, append([]string(nil), texts...))
+	if f.err != nil {
+		return nil, f.err
+	}
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		vec, ok := f.vectors[text]
+		if !ok {
+			vec = make([]float32, f.dimensions)
+			vec[len(text)%f.dimensions] = 1
+		}
+		out[i] = vec
+	}
+	return out, nil
+}
+
+func (f *fakeEmbedder) Model() string   { return f.model }
+func (f *fakeEmbedder) Dimensions() int { return f.dimensions }
+func (f *fakeEmbedder) Status() EmbeddingStatus {
+	return EmbeddingStatus{Provider: "fake", Model: f.model, Dimensions: f.dimensions}
+}
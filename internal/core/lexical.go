@@ -0,0 +1,117 @@
+package core
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// lexicalCandidateLimit bounds how many eligible full-text hits are considered per scan.
+const lexicalCandidateLimit = 200
+
+// lexicalPageSize is how many full-text hits are fetched per query while looking
+// for eligible ones.
+const lexicalPageSize = 200
+
+// LexicalHit is a task found by full-text search over titles and keywords.
+type LexicalHit struct {
+	IndexedTask
+	// Score is the negated BM25 rank: higher is better.
+	Score float64
+}
+
+// SearchLexical runs a BM25-ranked full-text query over active tasks of active agents,
+// matching their titles and keywords. Any keyword token may match; tasks matching more
+// (and rarer) tokens rank higher. mode (if set) and excludeAgentIDs are applied in the
+// query; eligible (if non-nil) is checked on each hit, and results are paged until
+// limit eligible hits are found, so filters apply before truncation. Returns nil if
+// the keywords contain no searchable tokens.
+func SearchLexical(db *sql.DB, keywords []string, mode string, excludeAgentIDs []string, eligible func(IndexedTask) bool, limit int) ([]LexicalHit, error) {
+	query := buildFTSQuery(keywords)
+	if query == "" {
+		return nil, nil
+	}
+
+	sqlQuery := `SELECT t.id, t.agent_id, t.mode, t.type, t.attributes, t.latitude, t.longitude, t.radius_km,
+		        -bm25(task_fts) AS score
+		 FROM task_fts
+		 JOIN tasks t ON t.id = task_fts.task_id
+		 JOIN agents a ON a.id = t.agent_id
+		 WHERE task_fts MATCH ?
+		   AND t.status = 'active'
+		   AND a.status = 'active'`
+	args := []interface{}{query}
+	if mode != "" {
+		sqlQuery += `
+		   AND t.mode = ?`
+		args = append(args, mode)
+	}
+	if len(excludeAgentIDs) > 0 {
+		sqlQuery += `
+		   AND t.agent_id NOT IN (` + strings.TrimSuffix(strings.Repeat("?,", len(excludeAgentIDs)), ",") + `)`
+		for _, id := range excludeAgentIDs {
+			args = append(args, id)
+		}
+	}
+	sqlQuery += `
+		 ORDER BY score DESC, t.id
+		 LIMIT ? OFFSET ?`
+
+	var hits []LexicalHit
+	for offset := 0; len(hits) < limit; offset += lexicalPageSize {
+		page, err := searchLexicalPage(db, sqlQuery, append(args, lexicalPageSize, offset))
+		if err != nil {
+			return nil, err
+		}
+		for _, h := range page {
+			if eligible == nil || eligible(h.IndexedTask) {
+				hits = append(hits, h)
+			}
+		}
+		if len(page) < lexicalPageSize {
+			break
+		}
+	}
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+func searchLexicalPage(db *sql.DB, query string, args []interface{}) ([]LexicalHit, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run full-text search: %w", err)
+	}
+	defer rows.Close()
+
+	var hits []LexicalHit
+	for rows.Next() {
+		var h LexicalHit
+		var attrsJSON string
+		var lat, lon sql.NullFloat64
+		var radius float64
+		if err := rows.Scan(&h.TaskID, &h.AgentID, &h.Mode, &h.Type, &attrsJSON, &lat, &lon, &radius, &h.Score); err != nil {
+			return nil, fmt.Errorf("failed to scan full-text hit: %w", err)
+		}
+		h.Attributes = parseTaskAttributes(attrsJSON)
+		h.Location = taskGeoPoint(lat, lon, radius)
+		hits = append(hits, h)
+	}
+	return hits, rows.Err()
+}
+
+// buildFTSQuery turns keywords into an FTS5 query matching any of their tokens.
+// Tokens only contain letters and digits, so quoting them is sufficient escaping.
+func buildFTSQuery(keywords []string) string {
+	seen := make(map[string]struct{})
+	var terms []string
+	for _, tok := range tokenize(strings.Join(keywords, " ")) {
+		if _, ok := seen[tok]; ok {
+			continue
+		}
+		seen[tok] = struct{}{}
+		terms = append(terms, `"`+tok+`"`)
+	}
+	return strings.Join(terms, " OR ")
+}
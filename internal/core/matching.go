@@ -3,37 +3,390 @@ package core
 import (
 	"database/sql"
 	"fmt"
+	"math"
 	"sort"
+	"strings"
 )
 
 // MatchResult holds information about a matched task.
+// ScoreForward is how well the task fits the query: SemanticScore (cosine similarity)
+// fused with LexicalScore (BM25 normalized to [0, 1] within the scan). In reciprocal
+// mode ScoreReverse is how well the query task fits the matched task, and Score is
+// the harmonic mean of both; otherwise (or without stored embeddings on both sides)
+// ScoreReverse is nil and Score equals ScoreForward. With outcome
+// re-ranking, Score is then multiplied by OutcomeBoost.
 type MatchResult struct {
-	AgentID     string  `json:"agent_id"`
-	TaskID      string  `json:"task_id"`
-	DisplayName string  `json:"display_name"`
-	PublicBio   string  `json:"public_bio"`
-	Mode        string  `json:"mode"`
-	Type        string  `json:"type"`
-	Title       string  `json:"title"`
-	Score       float64 `json:"score"`
+	AgentID       string   `json:"agent_id"`
+	TaskID        string   `json:"task_id"`
+	DisplayName   string   `json:"display_name"`
+	PublicBio     string   `json:"public_bio"`
+	Mode          string   `json:"mode"`
+	Type          string   `json:"type"`
+	Title         string   `json:"title"`
+	Score         float64  `json:"score"`
+	ScoreForward  float64  `json:"score_forward"`
+	ScoreReverse  *float64 `json:"score_reverse,omitempty"`
+	OutcomeBoost  float64  `json:"outcome_boost,omitempty"`
+	SemanticScore float64  `json:"semantic_score"`
+	LexicalScore  float64  `json:"lexical_score"`
+	// TypeRule is the compatibility rule that allowed this match, e.g. "hiring/job-seeking".
+	TypeRule string `json:"type_rule,omitempty"`
+	// ConversationID and ConversationState are set when the pair already has a
+	// conversation and contacted tasks were included.
+	ConversationID    string `json:"conversation_id,omitempty"`
+	ConversationState string `json:"conversation_state,omitempty"`
+	// DistanceKm is set when both tasks have a location.
+	DistanceKm *float64 `json:"distance_km,omitempty"`
+	// Explanation is filled in by ExplainMatches.
+	Explanation *MatchExplanation `json:"explanation,omitempty"`
 }
 
-// FindMatches loads all active task embeddings from the database, computes cosine similarity
-// with the query embedding, and returns the top matches above the minimum score.
-// heartbeatCutoff filters out agents that haven't been active since the given time (RFC3339).
-// Pass empty string to skip the filter.
-func FindMatches(db *sql.DB, queryEmbedding []float32, excludeAgentID string, maxResults int, minScore float64, heartbeatCutoff string) ([]MatchResult, error) {
+// hydrateBatchSize bounds how many index hits are looked up per database query.
+const hydrateBatchSize = 100
+
+// mmrPoolFactor is how many candidates per result slot diversity re-ranking chooses from.
+const mmrPoolFactor = 3
+
+// MatchOptions controls which tasks FindMatches may return. All filters are
+// applied before ranking and truncation, so MaxResults counts eligible tasks only.
+type MatchOptions struct {
+	// Mode restricts results to tasks in this mode ("beacon" or "radar"). Empty allows any.
+	Mode string
+	// TaskTypes restricts results to these task types. Empty allows any.
+	TaskTypes []string
+	// TypeRules, when non-nil, restricts results to task types it allows, as
+	// returned by CompatibleTaskTypes. Each result records the rule that applied.
+	TypeRules map[string]string
+	// ExcludeAgentIDs and ExcludeTaskIDs are never returned.
+	ExcludeAgentIDs []string
+	ExcludeTaskIDs  []string
+	// OnlyTaskIDs, when non-empty, limits results to these tasks. It is applied after
+	// scoring, so lexical scores are still normalized over every eligible task and a
+	// result scores exactly as in an unrestricted search.
+	OnlyTaskIDs []string
+	// Contacted holds tasks that already have a conversation with the query task,
+	// as returned by ContactedTasks. They are dropped unless IncludeContacted is
+	// set, in which case results are annotated with the conversation.
+	Contacted        map[string]Contact
+	IncludeContacted bool
+	MaxResults       int
+	MinScore         float64
+	// HeartbeatCutoff filters out agents that haven't been active since the given
+	// time (RFC3339). Empty skips the filter.
+	HeartbeatCutoff string
+	// Keywords feed the full-text index. LexicalWeight (0..1) is the share of the
+	// final score taken by the normalized BM25 score; 0 disables lexical search
+	// unless there is no query embedding, in which case it is the only signal.
+	Keywords      []string
+	LexicalWeight float64
+	// Filters are hard constraints on task attributes (see ValidateAttributeFilters).
+	// Tasks without a filtered attribute are excluded.
+	Filters map[string]interface{}
+	// Origin is the query task's location. Candidates are then limited to the radii
+	// set on either side (see geoMatch), and results report their distance.
+	Origin *GeoPoint
+	// ReciprocalTaskID enables reciprocal scoring: each candidate's stored embedding
+	// is also scored against this task's stored embedding, and results rank by the
+	// harmonic mean of both directions. Empty scores one way only.
+	ReciprocalTaskID string
+	// Priors, when non-nil, re-rank candidates by their conversation outcomes.
+	Priors *OutcomePriors
+	// MaxPerAgent caps how many results one agent can take; 0 disables the cap.
+	MaxPerAgent int
+	// Diversity (0..1) enables maximal-marginal-relevance re-ranking: each next result
+	// maximizes (1-Diversity)*score - Diversity*(similarity to results already picked).
+	// 0 keeps plain score order.
+	Diversity float64
+}
+
+// filter builds the index-level predicate for the options.
+func (o MatchOptions) filter() func(IndexedTask) bool {
+	types := toSet(o.TaskTypes)
+	excludedAgents := toSet(o.ExcludeAgentIDs)
+	excludedTasks := toSet(o.ExcludeTaskIDs)
+
+	return func(t IndexedTask) bool {
+		if o.Mode != "" && t.Mode != o.Mode {
+			return false
+		}
+		if len(types) > 0 {
+			if _, ok := types[t.Type]; !ok {
+				return false
+			}
+		}
+		if o.TypeRules != nil {
+			if _, ok := typeRule(o.TypeRules, t.Type); !ok {
+				return false
+			}
+		}
+		if _, ok := excludedAgents[t.AgentID]; ok {
+			return false
+		}
+		if _, ok := excludedTasks[t.TaskID]; ok {
+			return false
+		}
+		if _, ok := o.Contacted[t.TaskID]; ok && !o.IncludeContacted {
+			return false
+		}
+		if len(o.Filters) > 0 && !matchesAttributeFilters(t.Attributes, o.Filters) {
+			return false
+		}
+		if _, _, ok := geoMatch(o.Origin, t.Location); !ok {
+			return false
+		}
+		return true
+	}
+}
+
+// ComplementaryMode returns the mode a task should be matched against:
+// radar tasks see beacons, beacon tasks see radars.
+func ComplementaryMode(mode string) string {
+	if mode == "radar" {
+		return "beacon"
+	}
+	return "radar"
+}
+
+// FindMatches ranks tasks against the query using hybrid search: cosine similarity from
+// the vector index fused with BM25 from the full-text index as
+// (1-LexicalWeight)*semantic + LexicalWeight*lexical. A task qualifies if its semantic
+// or fused score reaches MinScore, so exact keyword terms such as skills or city names
+// that embeddings blur can lift a near miss over the threshold. With a nil
+// queryEmbedding (no embedding provider) ranking is keyword-only and any task matching
+// a keyword token qualifies.
+// Hits are then checked against the database, which drops tasks or agents that are no
+// longer active. Embeddings produced by a different model or dimension count cannot be
+// compared and are skipped; incompatible counts those of active tasks this query would
+// otherwise have considered, which the migrator will re-embed.
+func FindMatches(db *sql.DB, index *VectorIndex, queryEmbedding []float32, opts MatchOptions) (results []MatchResult, incompatible int, err error) {
+	filter := opts.filter()
+	candidates := make(map[string]*MatchResult)
+	// locations holds the located candidates, for reporting distance.
+	locations := make(map[string]*GeoPoint)
+
+	semanticWeight, lexicalWeight := 1-opts.LexicalWeight, opts.LexicalWeight
+	if queryEmbedding == nil {
+		semanticWeight, lexicalWeight = 0, 1
+	}
+
+	if queryEmbedding != nil {
+		hits, err := index.Search(queryEmbedding, 0, opts.MinScore, filter)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to search index: %w", err)
+		}
+		for _, h := range hits {
+			candidates[h.TaskID] = &MatchResult{TaskID: h.TaskID, Type: h.Type, SemanticScore: h.Score}
+			locations[h.TaskID] = h.Location
+		}
+	}
+
+	if lexicalWeight > 0 {
+		hits, err := SearchLexical(db, opts.Keywords, opts.Mode, opts.ExcludeAgentIDs, filter, lexicalCandidateLimit)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		// Normalize over eligible hits only.
+		var maxScore float64
+		for _, h := range hits {
+			maxScore = max(maxScore, h.Score)
+		}
+
+		for _, h := range hits {
+			lexical := 1.0
+			if maxScore > 0 {
+				lexical = h.Score / maxScore
+			}
+			m, ok := candidates[h.TaskID]
+			if !ok {
+				m = &MatchResult{TaskID: h.TaskID, Type: h.Type}
+				if queryEmbedding != nil {
+					m.SemanticScore, _ = index.Score(queryEmbedding, h.TaskID)
+				}
+				candidates[h.TaskID] = m
+				locations[h.TaskID] = h.Location
+			}
+			m.LexicalScore = lexical
+		}
+	}
+
+	only := toSet(opts.OnlyTaskIDs)
+	ranked := make([]MatchResult, 0, len(candidates))
+	for _, m := range candidates {
+		if _, ok := only[m.TaskID]; len(only) > 0 && !ok {
+			continue
+		}
+		m.ScoreForward = semanticWeight*m.SemanticScore + lexicalWeight*m.LexicalScore
+		// A keyword hit can lift a task over MinScore but not stand in for it.
+		if queryEmbedding != nil && m.SemanticScore < opts.MinScore && m.ScoreForward < opts.MinScore {
+			continue
+		}
+		m.Score = m.ScoreForward
+		if opts.ReciprocalTaskID != "" {
+			// Without a stored embedding on either side there is no reverse
+			// signal, and the forward score stands alone.
+			if reverse, ok := index.Similarity(m.TaskID, opts.ReciprocalTaskID); ok {
+				m.ScoreReverse = &reverse
+				m.Score = harmonicMean(m.ScoreForward, reverse)
+			}
+		}
+		if opts.Priors != nil {
+			m.OutcomeBoost = opts.Priors.Boost(m.TaskID, m.Type)
+			m.Score *= m.OutcomeBoost
+		}
+		ranked = append(ranked, *m)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		return ranked[i].Score > ranked[j].Score
+	})
+
+	// Hydrate candidates best-first until enough of them pass the status filters and
+	// the per-agent cap. Diversity re-ranking picks from a larger pool.
+	pool := opts.MaxResults
+	if opts.Diversity > 0 {
+		pool *= mmrPoolFactor
+	}
+	perAgent := make(map[string]int)
+	for start := 0; start < len(ranked) && len(results) < pool; start += hydrateBatchSize {
+		end := min(start+hydrateBatchSize, len(ranked))
+		batch, err := hydrateMatches(db, ranked[start:end], opts.HeartbeatCutoff)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, m := range batch {
+			if opts.MaxPerAgent > 0 && perAgent[m.AgentID] >= opts.MaxPerAgent {
+				continue
+			}
+			perAgent[m.AgentID]++
+			results = append(results, m)
+		}
+	}
+
+	if opts.Diversity > 0 {
+		results = selectDiverse(index, results, opts.MaxResults, opts.Diversity)
+	}
+
+	for i := range results {
+		if opts.TypeRules != nil {
+			results[i].TypeRule, _ = typeRule(opts.TypeRules, results[i].Type)
+		}
+		if contact, ok := opts.Contacted[results[i].TaskID]; ok {
+			results[i].ConversationID, results[i].ConversationState = contact.ConversationID, contact.State
+		}
+		if d, located, _ := geoMatch(opts.Origin, locations[results[i].TaskID]); located {
+			d = math.Round(d*10) / 10
+			results[i].DistanceKm = &d
+		}
+	}
+
+	// Limit to maxResults.
+	if len(results) > opts.MaxResults {
+		results = results[:opts.MaxResults]
+	}
+
+	if queryEmbedding != nil {
+		incompatible, err = countActiveTasks(db, index.IncompatibleTasks(filter))
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	return results, incompatible, nil
+}
+
+// countActiveTasks returns how many of the given tasks are active.
+func countActiveTasks(db *sql.DB, ids []string) (int, error) {
+	count := 0
+	for start := 0; start < len(ids); start += hydrateBatchSize {
+		batch := ids[start:min(start+hydrateBatchSize, len(ids))]
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(batch)), ",")
+		args := make([]interface{}, len(batch))
+		for i, id := range batch {
+			args[i] = id
+		}
+		var n int
+		err := db.QueryRow("SELECT COUNT(*) FROM tasks WHERE status = 'active' AND id IN ("+placeholders+")", args...).Scan(&n)
+		if err != nil {
+			return 0, fmt.Errorf("failed to count incompatible tasks: %w", err)
+		}
+		count += n
+	}
+	return count, nil
+}
+
+// selectDiverse picks up to n results by maximal marginal relevance. Similarity
+// between two results is the cosine of their stored embeddings; without embeddings,
+// results from the same agent count as identical and others as unrelated.
+func selectDiverse(index *VectorIndex, results []MatchResult, n int, diversity float64) []MatchResult {
+	remaining := results
+	selected := make([]MatchResult, 0, min(n, len(results)))
+
+	for len(selected) < n && len(remaining) > 0 {
+		best, bestValue := 0, math.Inf(-1)
+		for i, c := range remaining {
+			redundancy := 0.0
+			for _, s := range selected {
+				redundancy = max(redundancy, resultSimilarity(index, c, s))
+			}
+			if value := (1-diversity)*c.Score - diversity*redundancy; value > bestValue {
+				best, bestValue = i, value
+			}
+		}
+		selected = append(selected, remaining[best])
+		remaining = append(remaining[:best:best], remaining[best+1:]...)
+	}
+
+	return selected
+}
+
+func resultSimilarity(index *VectorIndex, a, b MatchResult) float64 {
+	if sim, ok := index.Similarity(a.TaskID, b.TaskID); ok {
+		return sim
+	}
+	if a.AgentID == b.AgentID {
+		return 1
+	}
+	return 0
+}
+
+// harmonicMean combines two scores so that a match ranks high only if both are
+// high. Non-positive inputs yield 0.
+func harmonicMean(a, b float64) float64 {
+	if a <= 0 || b <= 0 {
+		return 0
+	}
+	return 2 * a * b / (a + b)
+}
+
+// toSet converts a slice into a set for membership checks.
+func toSet(values []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		set[v] = struct{}{}
+	}
+	return set
+}
+
+// hydrateMatches fills in display fields for ranked candidates whose task and
+// agent are still active, preserving the candidates' order and scores.
+func hydrateMatches(db *sql.DB, candidates []MatchResult, heartbeatCutoff string) ([]MatchResult, error) {
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(candidates)), ",")
 	query := `
-		SELECT t.id, t.agent_id, t.mode, t.type, t.title, a.display_name, a.public_bio, te.embedding
+		SELECT t.id, t.agent_id, t.mode, t.type, t.title, a.display_name, a.public_bio
 		FROM tasks t
 		JOIN agents a ON t.agent_id = a.id
-		JOIN task_embeddings te ON t.id = te.task_id
 		WHERE a.status = 'active'
 		  AND t.status = 'active'
-		  AND t.agent_id != ?
+		  AND t.id IN (` + placeholders + `)
 	`
 
-	args := []interface{}{excludeAgentID}
+	args := make([]interface{}, 0, len(candidates)+1)
+	for _, m := range candidates {
+		args = append(args, m.TaskID)
+	}
 	if heartbeatCutoff != "" {
 		query += `  AND a.last_heartbeat >= ?
 	`
@@ -46,54 +399,25 @@ func FindMatches(db *sql.DB, queryEmbedding []float32, excludeAgentID string, ma
 	}
 	defer rows.Close()
 
-	var results []MatchResult
-
+	found := make(map[string]MatchResult, len(candidates))
 	for rows.Next() {
-		var (
-			taskID       string
-			agentID      string
-			mode         string
-			taskType     string
-			title        string
-			displayName  string
-			publicBio    string
-			embeddingRaw []byte
-		)
-
-		if err := rows.Scan(&taskID, &agentID, &mode, &taskType, &title, &displayName, &publicBio, &embeddingRaw); err != nil {
+		var m MatchResult
+		if err := rows.Scan(&m.TaskID, &m.AgentID, &m.Mode, &m.Type, &m.Title, &m.DisplayName, &m.PublicBio); err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
-
-		taskEmbedding := BytesToEmbedding(embeddingRaw)
-		score := CosineSimilarity(queryEmbedding, taskEmbedding)
-
-		if score >= minScore {
-			results = append(results, MatchResult{
-				AgentID:     agentID,
-				TaskID:      taskID,
-				DisplayName: displayName,
-				PublicBio:   publicBio,
-				Mode:        mode,
-				Type:        taskType,
-				Title:       title,
-				Score:       score,
-			})
-		}
+		found[m.TaskID] = m
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("row iteration error: %w", err)
 	}
 
-	// Sort by score descending.
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].Score > results[j].Score
-	})
-
-	// Limit to maxResults.
-	if len(results) > maxResults {
-		results = results[:maxResults]
+	var results []MatchResult
+	for _, c := range candidates {
+		if m, ok := found[c.TaskID]; ok {
+			c.AgentID, c.Mode, c.Type, c.Title, c.DisplayName, c.PublicBio = m.AgentID, m.Mode, m.Type, m.Title, m.DisplayName, m.PublicBio
+			results = append(results, c)
+		}
 	}
-
 	return results, nil
 }
@@ -0,0 +1,348 @@
+package core
+
+import (
+	"database/sql"
+	"math"
+	"sort"
+	"testing"
+)
+
+// unitAt returns a 2-dimensional unit vector whose cosine with (1, 0) is cos.
+func unitAt(cos float64) []float32 {
+	return []float32{float32(cos), float32(math.Sqrt(1 - cos*cos))}
+}
+
+// addTestBeacon inserts an active beacon and indexes vec for it (nil leaves it unembedded).
+func addTestBeacon(t *testing.T, database *sql.DB, index *VectorIndex, id, agentID, keywordsJSON string, vec []float32) {
+	t.Helper()
+	insertTestTask(t, database, id, agentID, "beacon", "hiring", id, keywordsJSON)
+	if vec != nil {
+		if err := SaveTaskEmbedding(database, index, id, vec, index.model); err != nil {
+			t.Fatalf("SaveTaskEmbedding(%s): %v", id, err)
+		}
+	}
+}
+
+func resultIDs(results []MatchResult) []string {
+	ids := make([]string, 0, len(results))
+	for _, r := range results {
+		ids = append(ids, r.TaskID)
+	}
+	return ids
+}
+
+func sortedIDs(results []MatchResult) []string {
+	ids := resultIDs(results)
+	sort.Strings(ids)
+	return ids
+}
+
+func equalIDs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestFindMatchesKeywordHitsMustReachMinScore(t *testing.T) {
+	database := openTestDB(t)
+	index := NewVectorIndex("m", 2)
+	insertTestAgent(t, database, "agent")
+	addTestBeacon(t, database, index, "semantic", "agent", `["go"]`, unitAt(0.9))
+	addTestBeacon(t, database, index, "lifted", "agent", `["rust"]`, unitAt(0.6))
+	addTestBeacon(t, database, index, "keyword-only", "agent", `["rust"]`, unitAt(0.1))
+	addTestBeacon(t, database, index, "unembedded", "agent", `["rust"]`, nil)
+
+	tests := []struct {
+		name      string
+		embedding []float32
+		want      []string
+	}{
+		// 0.7*0.6 + 0.3*1 reaches 0.7; 0.7*0.1 + 0.3*1 does not.
+		{"hybrid", []float32{1, 0}, []string{"lifted", "semantic"}},
+		// Without an embedding, keywords are the only signal.
+		{"keyword-only search", nil, []string{"keyword-only", "lifted", "unembedded"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, _, err := FindMatches(database, index, tt.embedding, MatchOptions{
+				Mode:          "beacon",
+				MaxResults:    10,
+				MinScore:      0.7,
+				Keywords:      []string{"rust"},
+				LexicalWeight: 0.3,
+			})
+			if err != nil {
+				t.Fatalf("FindMatches: %v", err)
+			}
+			if got := sortedIDs(results); !equalIDs(got, tt.want) {
+				t.Errorf("results = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchLexicalFiltersBeforeTruncation(t *testing.T) {
+	database := openTestDB(t)
+	insertTestAgent(t, database, "crowd")
+	insertTestAgent(t, database, "other")
+
+	// More ineligible hits than one page, all ranking above the eligible one.
+	for i := 0; i < lexicalPageSize+20; i++ {
+		id := "crowd-" + string(rune('a'+i/26)) + string(rune('a'+i%26))
+		insertTestTask(t, database, id, "crowd", "beacon", "hiring", "rust", `["rust"]`)
+	}
+	insertTestTask(t, database, "radar", "other", "radar", "hiring", "rust", `["rust"]`)
+	insertTestTask(t, database, "wanted", "other", "beacon", "dating", "rust engineer in shanghai", `["rust", "engineer", "shanghai"]`)
+
+	tests := []struct {
+		name     string
+		mode     string
+		exclude  []string
+		eligible func(IndexedTask) bool
+		want     []string
+	}{
+		{"mode and agents in the query", "beacon", []string{"crowd"}, nil, []string{"wanted"}},
+		{"predicate pages past ineligible hits", "beacon", nil, func(t IndexedTask) bool { return t.Type == "dating" }, []string{"wanted"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits, err := SearchLexical(database, []string{"rust"}, tt.mode, tt.exclude, tt.eligible, 1)
+			if err != nil {
+				t.Fatalf("SearchLexical: %v", err)
+			}
+			var got []string
+			for _, h := range hits {
+				got = append(got, h.TaskID)
+			}
+			if !equalIDs(got, tt.want) {
+				t.Errorf("hits = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchLexicalNoTokens(t *testing.T) {
+	hits, err := SearchLexical(openTestDB(t), []string{"  ", "!!"}, "", nil, nil, 10)
+	if err != nil || hits != nil {
+		t.Errorf("SearchLexical = %v, %v; want nil, nil", hits, err)
+	}
+}
+
+func TestFindMatchesOnlyTaskIDsKeepsScores(t *testing.T) {
+	database := openTestDB(t)
+	index := NewVectorIndex("m", 2)
+	insertTestAgent(t, database, "agent")
+	addTestBeacon(t, database, index, "strong", "agent", `["rust", "go", "shanghai"]`, unitAt(0.9))
+	addTestBeacon(t, database, index, "weak", "agent", `["rust", "python", "java", "remote", "cloud"]`, unitAt(0.9))
+
+	opts := MatchOptions{
+		Mode:          "beacon",
+		MaxResults:    10,
+		Keywords:      []string{"rust", "go", "shanghai"},
+		LexicalWeight: 0.3,
+	}
+	all, _, err := FindMatches(database, index, []float32{1, 0}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.OnlyTaskIDs = []string{"weak"}
+	only, _, err := FindMatches(database, index, []float32{1, 0}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !equalIDs(resultIDs(only), []string{"weak"}) {
+		t.Fatalf("results = %v, want [weak]", resultIDs(only))
+	}
+	for _, m := range all {
+		if m.TaskID == "weak" && m.Score != only[0].Score {
+			t.Errorf("restricted score = %v, unrestricted %v", only[0].Score, m.Score)
+		}
+	}
+	if only[0].LexicalScore >= 1 {
+		t.Errorf("lexical score %v normalized against the restricted set only", only[0].LexicalScore)
+	}
+}
+
+func TestFindMatchesScoreReverse(t *testing.T) {
+	database := openTestDB(t)
+	index := NewVectorIndex("m", 2)
+	insertTestAgent(t, database, "agent")
+	insertTestAgent(t, database, "seeker")
+	addTestBeacon(t, database, index, "beacon", "agent", `["go"]`, []float32{0, 1})
+	addTestBeacon(t, database, index, "unembedded", "agent", `["go"]`, nil)
+	insertTestTask(t, database, "radar", "seeker", "radar", "job-seeking", "radar", `["go"]`)
+	if err := SaveTaskEmbedding(database, index, "radar", []float32{1, 0}, "m"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		reciprocal string
+		want       map[string]*float64
+	}{
+		{"one way", "", map[string]*float64{"beacon": nil, "unembedded": nil}},
+		// The beacon is orthogonal to the radar: a real reverse score of 0.
+		{"reciprocal", "radar", map[string]*float64{"beacon": new(float64), "unembedded": nil}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, _, err := FindMatches(database, index, []float32{0, 1}, MatchOptions{
+				Mode:             "beacon",
+				MaxResults:       10,
+				Keywords:         []string{"go"},
+				LexicalWeight:    0.5,
+				ReciprocalTaskID: tt.reciprocal,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != len(tt.want) {
+				t.Fatalf("results = %v, want %d", resultIDs(results), len(tt.want))
+			}
+			for _, m := range results {
+				want := tt.want[m.TaskID]
+				if (m.ScoreReverse == nil) != (want == nil) || (want != nil && *m.ScoreReverse != *want) {
+					t.Errorf("%s: ScoreReverse = %v, want %v", m.TaskID, m.ScoreReverse, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFindMatchesMaxPerAgent(t *testing.T) {
+	database := openTestDB(t)
+	index := NewVectorIndex("m", 2)
+	insertTestAgent(t, database, "prolific")
+	insertTestAgent(t, database, "other")
+	addTestBeacon(t, database, index, "p1", "prolific", `["go"]`, unitAt(0.99))
+	addTestBeacon(t, database, index, "p2", "prolific", `["go"]`, unitAt(0.98))
+	addTestBeacon(t, database, index, "p3", "prolific", `["go"]`, unitAt(0.97))
+	addTestBeacon(t, database, index, "o1", "other", `["go"]`, unitAt(0.8))
+
+	tests := []struct {
+		name        string
+		maxPerAgent int
+		diversity   float64
+		want        []string
+	}{
+		{"no cap", 0, 0, []string{"p1", "p2", "p3"}},
+		{"cap of one", 1, 0, []string{"p1", "o1"}},
+		{"cap of two", 2, 0, []string{"p1", "p2", "o1"}},
+		{"cap holds with diversity", 1, 0.5, []string{"p1", "o1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, _, err := FindMatches(database, index, []float32{1, 0}, MatchOptions{
+				Mode:        "beacon",
+				MaxResults:  3,
+				MaxPerAgent: tt.maxPerAgent,
+				Diversity:   tt.diversity,
+			})
+			if err != nil {
+				t.Fatalf("FindMatches: %v", err)
+			}
+			if got := resultIDs(results); !equalIDs(got, tt.want) {
+				t.Errorf("results = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectDiverse(t *testing.T) {
+	index := NewVectorIndex("m", 2)
+	index.mu.Lock()
+	index.upsertLocked(IndexedTask{TaskID: "a"}, []float32{1, 0}, "m", 2)
+	index.upsertLocked(IndexedTask{TaskID: "a-twin"}, []float32{1, 0.01}, "m", 2)
+	index.upsertLocked(IndexedTask{TaskID: "b"}, []float32{0, 1}, "m", 2)
+	index.mu.Unlock()
+
+	embedded := []MatchResult{
+		{TaskID: "a", AgentID: "x", Score: 0.9},
+		{TaskID: "a-twin", AgentID: "y", Score: 0.85},
+		{TaskID: "b", AgentID: "z", Score: 0.6},
+	}
+	// Without embeddings, results of the same agent count as identical.
+	unembedded := []MatchResult{
+		{TaskID: "x1", AgentID: "x", Score: 0.9},
+		{TaskID: "x2", AgentID: "x", Score: 0.85},
+		{TaskID: "y1", AgentID: "y", Score: 0.6},
+	}
+
+	tests := []struct {
+		name      string
+		results   []MatchResult
+		n         int
+		diversity float64
+		want      []string
+	}{
+		{"no diversity keeps score order", embedded, 3, 0, []string{"a", "a-twin", "b"}},
+		{"near duplicate pushed down", embedded, 3, 0.5, []string{"a", "b", "a-twin"}},
+		{"truncates to n", embedded, 2, 0.5, []string{"a", "b"}},
+		{"slight diversity keeps order", embedded, 3, 0.1, []string{"a", "a-twin", "b"}},
+		{"same agent without embeddings", unembedded, 2, 0.5, []string{"x1", "y1"}},
+		{"n larger than results", embedded[:1], 3, 0.5, []string{"a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := append([]MatchResult(nil), tt.results...)
+			if got := resultIDs(selectDiverse(index, in, tt.n, tt.diversity)); !equalIDs(got, tt.want) {
+				t.Errorf("selectDiverse = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindMatchesCountsIncompatibleForQuery(t *testing.T) {
+	database := openTestDB(t)
+	index := NewVectorIndex("m", 2)
+	insertTestAgent(t, database, "agent")
+	insertTestAgent(t, database, "excluded")
+	addTestBeacon(t, database, index, "current", "agent", `["go"]`, []float32{1, 0})
+	insertTestTask(t, database, "stale", "agent", "beacon", "hiring", "stale", `["go"]`)
+	insertTestTask(t, database, "stale-radar", "agent", "radar", "hiring", "stale", `["go"]`)
+	insertTestTask(t, database, "stale-paused", "agent", "beacon", "hiring", "stale", `["go"]`)
+	insertTestTask(t, database, "stale-excluded", "excluded", "beacon", "hiring", "stale", `["go"]`)
+	if _, err := database.Exec("UPDATE tasks SET status = 'paused' WHERE id = 'stale-paused'"); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"stale", "stale-radar", "stale-paused", "stale-excluded"} {
+		if err := SaveTaskEmbedding(database, index, id, []float32{1, 0, 0}, "old"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		embedding []float32
+		want      int
+	}{
+		// Only the active beacon of a non-excluded agent would have been considered.
+		{"semantic search", []float32{1, 0}, 1},
+		// Keyword-only search still finds stale tasks through the full-text index.
+		{"keyword-only search", nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, incompatible, err := FindMatches(database, index, tt.embedding, MatchOptions{
+				Mode:            "beacon",
+				MaxResults:      10,
+				ExcludeAgentIDs: []string{"excluded"},
+				Keywords:        []string{"go"},
+				LexicalWeight:   0.3,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if incompatible != tt.want {
+				t.Errorf("incompatible = %d, want %d (index-wide %d)", incompatible, tt.want, index.Incompatible())
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package core
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Conversation notification types.
+const (
+	NotificationConversationAccepted = "conversation_accepted"
+	NotificationConversationDeclined = "conversation_declined"
+)
+
+// execer is implemented by *sql.DB and *sql.Tx, for helpers that run both inside
+// and outside a transaction.
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// ConversationNotice is a conversation notification for AgentID, queued together
+// with a transition by TransitionConversation.
+type ConversationNotice struct {
+	AgentID string
+	Type    string
+	Message string
+	Reason  string
+}
+
+// queueConversationNotification queues a notification for agentID about a change
+// fromAgentID made to a conversation. It is delivered on agentID's next heartbeat.
+// Callers write it in the transaction that makes the change.
+func queueConversationNotification(db execer, conversationID, agentID, fromAgentID, notificationType, message, reason string) error {
+	now := time.Now().UTC()
+	_, err := db.Exec(
+		`INSERT INTO conversation_notifications (id, conversation_id, agent_id, from_agent_id, type, message, reason, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		GenerateMD5(conversationID, agentID, notificationType, now.Format(time.RFC3339Nano)),
+		conversationID, agentID, fromAgentID, notificationType, message, reason, now.Format(time.RFC3339),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to queue conversation notification: %w", err)
+	}
+	return nil
+}
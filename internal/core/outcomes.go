@@ -0,0 +1,200 @@
+package core
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"agentsocial/internal/config"
+)
+
+// outcomePriorStrength is how many pseudo-conversations of the parent prior (global
+// for task types, task type for tasks) are blended into each rate, so a task with
+// one lucky conversation is not ranked as a sure thing.
+const outcomePriorStrength = 5.0
+
+// Bounds on the outcome re-ranking multiplier.
+const (
+	minOutcomeBoost = 0.5
+	maxOutcomeBoost = 1.5
+)
+
+// outcomeCounts aggregates conversation outcomes for a task or task type.
+type outcomeCounts struct {
+	resolved  float64 // approached as target and answered, or left to expire; requests the initiator withdrew are left out
+	accepted  float64 // approached as target and accepted
+	concluded float64 // concluded either way
+	matched   float64 // concluded_matched
+}
+
+// outcomeRates are smoothed acceptance and match rates.
+type outcomeRates struct {
+	acceptance float64
+	match      float64
+}
+
+func (c outcomeCounts) smooth(parent outcomeRates) outcomeRates {
+	return outcomeRates{
+		acceptance: (c.accepted + outcomePriorStrength*parent.acceptance) / (c.resolved + outcomePriorStrength),
+		match:      (c.matched + outcomePriorStrength*parent.match) / (c.concluded + outcomePriorStrength),
+	}
+}
+
+func (r outcomeRates) quality() float64 {
+	return (r.acceptance + r.match) / 2
+}
+
+// OutcomePriors holds per-task-type and per-task outcome rates learnt from concluded
+// conversations: how often a task accepts conversations when approached, and how
+// often its conversations end in a match. Rates are smoothed towards the task type,
+// and task types towards the platform average. Safe for concurrent use.
+type OutcomePriors struct {
+	mu     sync.RWMutex
+	weight float64
+	global outcomeRates
+	byType map[string]outcomeRates
+	byTask map[string]outcomeRates
+}
+
+// NewOutcomePriors creates empty priors. weight scales how far a task's outcome
+// quality above or below the platform average moves its score.
+func NewOutcomePriors(weight float64) *OutcomePriors {
+	return &OutcomePriors{
+		weight: weight,
+		global: outcomeRates{acceptance: 0.5, match: 0.5},
+		byType: make(map[string]outcomeRates),
+		byTask: make(map[string]outcomeRates),
+	}
+}
+
+// Boost returns the score multiplier for a candidate task: 1 for an average task,
+// higher for tasks whose conversations tend to be accepted and end in a match.
+func (p *OutcomePriors) Boost(taskID, taskType string) float64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	rates, ok := p.byTask[taskID]
+	if !ok {
+		rates, ok = p.byType[normalizeTaskType(taskType)]
+	}
+	if !ok {
+		return 1
+	}
+
+	boost := 1 + p.weight*(rates.quality()-p.global.quality())
+	return math.Max(minOutcomeBoost, math.Min(maxOutcomeBoost, boost))
+}
+
+// Recompute rebuilds the priors from the conversations table.
+func (p *OutcomePriors) Recompute(db *sql.DB) error {
+	perTask, taskTypes, err := loadOutcomeCounts(db)
+	if err != nil {
+		return err
+	}
+
+	var total outcomeCounts
+	perType := make(map[string]outcomeCounts)
+	for id, c := range perTask {
+		taskType := taskTypes[id]
+		t := perType[taskType]
+		t.resolved += c.resolved
+		t.accepted += c.accepted
+		t.concluded += c.concluded
+		t.matched += c.matched
+		perType[taskType] = t
+
+		total.resolved += c.resolved
+		total.accepted += c.accepted
+		total.concluded += c.concluded
+		total.matched += c.matched
+	}
+
+	global := total.smooth(outcomeRates{acceptance: 0.5, match: 0.5})
+	byType := make(map[string]outcomeRates, len(perType))
+	for taskType, c := range perType {
+		byType[taskType] = c.smooth(global)
+	}
+	byTask := make(map[string]outcomeRates, len(perTask))
+	for id, c := range perTask {
+		byTask[id] = c.smooth(byType[taskTypes[id]])
+	}
+
+	p.mu.Lock()
+	p.global, p.byType, p.byTask = global, byType, byTask
+	p.mu.Unlock()
+
+	return nil
+}
+
+// loadOutcomeCounts counts each task's conversation outcomes, and returns the tasks'
+// normalized types. Acceptance comes from the accept events in the conversation
+// history; a request that ended without one (declined, expired, or concluded while
+// pending) was not accepted, and one the initiator withdrew while pending does not
+// count against the target. Conversations from before the history was kept count
+// as accepted if they reached the active state.
+func loadOutcomeCounts(db *sql.DB) (map[string]outcomeCounts, map[string]string, error) {
+	rows, err := db.Query(
+		`WITH outcomes AS (
+		   SELECT c.initiator_task, c.target_task, c.state,
+		          EXISTS (SELECT 1 FROM conversation_transitions ct
+		                  WHERE ct.conversation_id = c.id AND ct.event = 'accept') AS accepted,
+		          EXISTS (SELECT 1 FROM conversation_transitions ct
+		                  WHERE ct.conversation_id = c.id) AS has_history,
+		          EXISTS (SELECT 1 FROM conversation_transitions ct
+		                  WHERE ct.conversation_id = c.id AND ct.event = 'conclude_no_match'
+		                    AND ct.from_state = 'pending_acceptance' AND ct.actor = c.initiator_agent) AS withdrawn
+		   FROM conversations c
+		 )
+		 SELECT t.id, t.type,
+		        SUM(CASE WHEN o.target_task = t.id AND o.state != 'pending_acceptance' AND NOT o.withdrawn THEN 1 ELSE 0 END),
+		        SUM(CASE WHEN o.target_task = t.id
+		                  AND (o.accepted OR (NOT o.has_history AND o.state IN ('active', 'concluded_matched'))) THEN 1 ELSE 0 END),
+		        SUM(CASE WHEN o.state IN ('concluded_matched', 'concluded_no_match') THEN 1 ELSE 0 END),
+		        SUM(CASE WHEN o.state = 'concluded_matched' THEN 1 ELSE 0 END)
+		 FROM outcomes o
+		 JOIN tasks t ON t.id IN (o.initiator_task, o.target_task)
+		 GROUP BY t.id`,
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to aggregate conversation outcomes: %w", err)
+	}
+	defer rows.Close()
+
+	perTask := make(map[string]outcomeCounts)
+	taskTypes := make(map[string]string)
+	for rows.Next() {
+		var id, taskType string
+		var c outcomeCounts
+		if err := rows.Scan(&id, &taskType, &c.resolved, &c.accepted, &c.concluded, &c.matched); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan conversation outcomes: %w", err)
+		}
+		perTask[id] = c
+		taskTypes[id] = normalizeTaskType(taskType)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return perTask, taskTypes, nil
+}
+
+// StartOutcomePriorsJob recomputes the priors now and then periodically.
+func StartOutcomePriorsJob(db *sql.DB, priors *OutcomePriors, cfg *config.Config) {
+	recompute := func() {
+		if err := priors.Recompute(db); err != nil {
+			log.Printf("Outcome priors error: %v", err)
+		}
+	}
+
+	recompute()
+	if cfg.OutcomeRerankIntervalSeconds <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(cfg.OutcomeRerankIntervalSeconds) * time.Second)
+	for range ticker.C {
+		recompute()
+	}
+}
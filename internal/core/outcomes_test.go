@@ -0,0 +1,93 @@
+package core
+
+import (
+	"math"
+	"testing"
+)
+
+func TestOutcomeCountsSmooth(t *testing.T) {
+	parent := outcomeRates{acceptance: 0.5, match: 0.2}
+	tests := []struct {
+		name   string
+		counts outcomeCounts
+		want   outcomeRates
+	}{
+		{"no history keeps the parent", outcomeCounts{}, parent},
+		// (1 + 5*0.5) / (1 + 5) and (1 + 5*0.2) / (1 + 5).
+		{"one lucky conversation", outcomeCounts{resolved: 1, accepted: 1, concluded: 1, matched: 1}, outcomeRates{acceptance: 3.5 / 6, match: 2.0 / 6}},
+		{"long history dominates", outcomeCounts{resolved: 995, accepted: 0, concluded: 995, matched: 995}, outcomeRates{acceptance: 2.5 / 1000, match: 996.0 / 1000}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.counts.smooth(parent)
+			if math.Abs(got.acceptance-tt.want.acceptance) > 1e-9 || math.Abs(got.match-tt.want.match) > 1e-9 {
+				t.Errorf("smooth = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutcomePriorsBoost(t *testing.T) {
+	p := NewOutcomePriors(2)
+	p.byType["hiring"] = outcomeRates{acceptance: 0.7, match: 0.5}
+	p.byTask["great"] = outcomeRates{acceptance: 1, match: 1}
+	p.byTask["poor"] = outcomeRates{acceptance: 0, match: 0}
+
+	tests := []struct {
+		taskID, taskType string
+		want             float64
+	}{
+		{"great", "hiring", maxOutcomeBoost}, // 1 + 2*0.5 = 2, clamped
+		{"poor", "hiring", minOutcomeBoost},  // 1 - 2*0.5 = 0, clamped
+		{"new", "Hiring", 1.2},               // falls back to the type: 1 + 2*0.1
+		{"new", "dating", 1},                 // no history at all
+	}
+	for _, tt := range tests {
+		if got := p.Boost(tt.taskID, tt.taskType); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Boost(%s, %s) = %v, want %v", tt.taskID, tt.taskType, got, tt.want)
+		}
+	}
+}
+
+func TestLoadOutcomeCounts(t *testing.T) {
+	database := openTestDB(t)
+	insertTestParticipants(t, database)
+
+	create := [3]string{EventCreate, "", "initiator"}
+	insertTestConversation(t, database, "accepted-then-ended", StateConcludedNoMatch,
+		create, [3]string{EventAccept, StatePendingAcceptance, "target"}, [3]string{EventConcludeNoMatch, StateActive, "initiator"})
+	insertTestConversation(t, database, "withdrawn", StateConcludedNoMatch,
+		create, [3]string{EventConcludeNoMatch, StatePendingAcceptance, "initiator"})
+	insertTestConversation(t, database, "closed-by-target", StateConcludedNoMatch,
+		create, [3]string{EventConcludeNoMatch, StatePendingAcceptance, "target"})
+	insertTestConversation(t, database, "declined", StateDeclined,
+		create, [3]string{EventDecline, StatePendingAcceptance, "target"})
+	insertTestConversation(t, database, "expired", StateExpired,
+		create, [3]string{EventExpire, StatePendingAcceptance, ActorSystem})
+	insertTestConversation(t, database, "pending", StatePendingAcceptance, create)
+	// Conversations from before the history was kept.
+	insertTestConversation(t, database, "legacy-active", StateActive)
+	insertTestConversation(t, database, "legacy-matched", StateConcludedMatched)
+	insertTestConversation(t, database, "legacy-no-match", StateConcludedNoMatch)
+
+	counts, types, err := loadOutcomeCounts(database)
+	if err != nil {
+		t.Fatalf("loadOutcomeCounts: %v", err)
+	}
+
+	tests := []struct {
+		taskID, taskType string
+		want             outcomeCounts
+	}{
+		{"offer", "hiring", outcomeCounts{resolved: 7, accepted: 3, concluded: 5, matched: 1}},
+		{"ask", "job-seeking", outcomeCounts{resolved: 0, accepted: 0, concluded: 5, matched: 1}},
+	}
+	for _, tt := range tests {
+		if got := counts[tt.taskID]; got != tt.want {
+			t.Errorf("%s: counts = %+v, want %+v", tt.taskID, got, tt.want)
+		}
+		if types[tt.taskID] != tt.taskType {
+			t.Errorf("%s: type = %q, want %q", tt.taskID, types[tt.taskID], tt.taskType)
+		}
+	}
+}
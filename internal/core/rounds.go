@@ -0,0 +1,169 @@
+package core
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Matching protocol rounds: agents evaluate each other, then the radar-side human
+// talks to the beacon agent, then the humans talk directly.
+const (
+	RoundAgentAgent = 1
+	RoundHumanAgent = 2
+	RoundHumanHuman = 3
+)
+
+// Round transitions. An escalation takes effect once both participants have
+// requested it; CancelEscalation withdraws or refuses a pending request.
+const (
+	TransitionEscalateToRound2 = "escalate_to_round2"
+	TransitionEscalateToRound3 = "escalate_to_round3"
+	TransitionCancelEscalation = "cancel_escalation"
+)
+
+// Round notification types.
+const (
+	NotificationEscalationRequested = "escalation_requested"
+	NotificationEscalationCancelled = "escalation_cancelled"
+	NotificationRoundAdvanced       = "round_advanced"
+)
+
+// ErrUnknownTransition is returned for a transition name that does not exist.
+var ErrUnknownTransition = errors.New("unknown transition")
+
+// TransitionError reports a transition that is not allowed in the conversation's
+// current state or round.
+type TransitionError struct {
+	Transition string
+	State      string
+	Round      int
+	Reason     string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("%s is not allowed: %s", e.Transition, e.Reason)
+}
+
+// RoundStatus is a conversation's round after a round transition.
+type RoundStatus struct {
+	Round                 int    `json:"round"`
+	PendingTransition     string `json:"pending_transition,omitempty"`
+	TransitionRequestedBy string `json:"transition_requested_by,omitempty"`
+	// Advanced is true when this call confirmed an escalation and the round moved on.
+	Advanced bool `json:"advanced"`
+}
+
+// ApplyRoundTransition requests, confirms or cancels a round escalation on behalf of
+// agentID, who must be a participant. Escalations are only allowed on active
+// conversations, one round at a time: the first participant's request is recorded
+// and the counterpart notified; the same request from the counterpart advances the
+// round. Repeating one's own request is a no-op. Requests, confirmations and
+// cancellations are written to the conversation's history in the same transaction.
+func ApplyRoundTransition(db *sql.DB, conversationID, agentID, transition string) (*RoundStatus, error) {
+	if t, ok := conversationTransitions[transition]; !ok || !t.round {
+		return nil, ErrUnknownTransition
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var initiatorAgent, targetAgent, state, pending, requestedBy string
+	var round int
+	err = tx.QueryRow(
+		`SELECT initiator_agent, target_agent, state, round, pending_transition, transition_requested_by
+		 FROM conversations WHERE id = ?`,
+		conversationID,
+	).Scan(&initiatorAgent, &targetAgent, &state, &round, &pending, &requestedBy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up conversation: %w", err)
+	}
+	escalation, err := checkTransition(transition, state, round)
+	if err != nil {
+		return nil, err
+	}
+
+	counterpart := initiatorAgent
+	if agentID == initiatorAgent {
+		counterpart = targetAgent
+	}
+	status := &RoundStatus{Round: round, PendingTransition: pending, TransitionRequestedBy: requestedBy}
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	var query, notificationType, message string
+	var args []interface{}
+	switch {
+	case transition == TransitionCancelEscalation:
+		if pending == "" {
+			return nil, &TransitionError{Transition: transition, State: state, Round: round, Reason: "no escalation is pending"}
+		}
+		query = `UPDATE conversations SET pending_transition = '', transition_requested_by = '', updated_at = ?
+			 WHERE id = ? AND state = 'active' AND round = ? AND pending_transition = ?`
+		args = []interface{}{now, conversationID, round, pending}
+		status.PendingTransition, status.TransitionRequestedBy = "", ""
+		notificationType, message = NotificationEscalationCancelled, "Escalation to the next round was cancelled"
+
+	case pending == transition && requestedBy == agentID:
+		// Already requested; still waiting for the counterpart.
+		return status, nil
+
+	case pending == transition:
+		// The counterpart asked first: confirm and advance.
+		query = `UPDATE conversations SET round = ?, pending_transition = '', transition_requested_by = '', updated_at = ?
+			 WHERE id = ? AND state = 'active' AND round = ? AND pending_transition = ?`
+		args = []interface{}{escalation.toRound, now, conversationID, round, pending}
+		status.Round, status.PendingTransition, status.TransitionRequestedBy, status.Advanced = escalation.toRound, "", "", true
+		notificationType = NotificationRoundAdvanced
+		message = fmt.Sprintf("Escalation confirmed: the conversation is now in round %d", escalation.toRound)
+
+	default:
+		query = `UPDATE conversations SET pending_transition = ?, transition_requested_by = ?, updated_at = ?
+			 WHERE id = ? AND state = 'active' AND round = ? AND pending_transition = ''`
+		args = []interface{}{transition, agentID, now, conversationID, round}
+		status.PendingTransition, status.TransitionRequestedBy = transition, agentID
+		notificationType = NotificationEscalationRequested
+		message = fmt.Sprintf("Escalation to round %d requested; confirm with %s", escalation.toRound, transition)
+	}
+
+	ok, err := updateRound(tx, query, args...)
+	if err != nil || !ok {
+		return nil, concurrentRoundChange(err, transition, state, round)
+	}
+	// A request or cancellation leaves the round as it is; the history tells them
+	// apart from a confirmation by its to_round.
+	if err := recordConversationTransition(tx, conversationID, transition, state, escalation.to, round, status.Round, agentID, "", now); err != nil {
+		return nil, err
+	}
+	// The transition stands even if the counterpart cannot be notified.
+	if err := queueConversationNotification(tx, conversationID, counterpart, agentID, notificationType, message, ""); err != nil {
+		log.Printf("Conversation %s: %v", conversationID, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit round transition: %w", err)
+	}
+	return status, nil
+}
+
+// updateRound runs a guarded update and reports whether it matched a row.
+func updateRound(db execer, query string, args ...interface{}) (bool, error) {
+	result, err := db.Exec(query, args...)
+	if err != nil {
+		return false, fmt.Errorf("failed to update conversation round: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+// concurrentRoundChange turns a guarded update that matched no row into a
+// TransitionError: the other participant changed the round in the meantime.
+func concurrentRoundChange(err error, transition, state string, round int) error {
+	if err != nil {
+		return err
+	}
+	return &TransitionError{Transition: transition, State: state, Round: round, Reason: "the conversation changed concurrently, retry"}
+}
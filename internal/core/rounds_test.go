@@ -0,0 +1,131 @@
+package core
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCancelEscalationRequiresActiveConversation(t *testing.T) {
+	tests := []struct {
+		state   string
+		pending string
+		wantErr bool
+	}{
+		{StateActive, TransitionEscalateToRound2, false},
+		{StateActive, "", true},
+		{StateConcludedMatched, TransitionEscalateToRound2, true},
+		{StateConcludedNoMatch, TransitionEscalateToRound2, true},
+		{StatePendingAcceptance, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.state+"/"+tt.pending, func(t *testing.T) {
+			database := openTestDB(t)
+			insertTestParticipants(t, database)
+			insertTestConversation(t, database, "conv", tt.state)
+			if _, err := database.Exec(
+				"UPDATE conversations SET pending_transition = ?, transition_requested_by = 'initiator' WHERE id = 'conv'",
+				tt.pending,
+			); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := ApplyRoundTransition(database, "conv", "target", TransitionCancelEscalation)
+			var transitionErr *TransitionError
+			if tt.wantErr != errors.As(err, &transitionErr) {
+				t.Fatalf("err = %v, want TransitionError %v", err, tt.wantErr)
+			}
+
+			var pending string
+			if err := database.QueryRow("SELECT pending_transition FROM conversations WHERE id = 'conv'").Scan(&pending); err != nil {
+				t.Fatal(err)
+			}
+			if wantPending := map[bool]string{true: tt.pending, false: ""}[tt.wantErr]; pending != wantPending {
+				t.Errorf("pending_transition = %q, want %q", pending, wantPending)
+			}
+		})
+	}
+}
+
+func TestRoundEscalationSequence(t *testing.T) {
+	type step struct {
+		agent, transition string
+		wantErr           bool
+		wantRound         int
+		wantPending       string
+	}
+	tests := []struct {
+		name        string
+		steps       []step
+		wantHistory []string
+	}{
+		{
+			"request and confirm",
+			[]step{
+				{"initiator", TransitionEscalateToRound2, false, 1, TransitionEscalateToRound2},
+				{"initiator", TransitionEscalateToRound2, false, 1, TransitionEscalateToRound2},
+				{"target", TransitionEscalateToRound2, false, 2, ""},
+			},
+			[]string{"escalate_to_round2 1->1", "escalate_to_round2 1->2"},
+		},
+		{
+			"cancel then request again",
+			[]step{
+				{"initiator", TransitionEscalateToRound2, false, 1, TransitionEscalateToRound2},
+				{"target", TransitionCancelEscalation, false, 1, ""},
+				{"target", TransitionCancelEscalation, true, 1, ""},
+				{"target", TransitionEscalateToRound2, false, 1, TransitionEscalateToRound2},
+			},
+			[]string{"escalate_to_round2 1->1", "cancel_escalation 1->1", "escalate_to_round2 1->1"},
+		},
+		{
+			"one round at a time",
+			[]step{
+				{"initiator", TransitionEscalateToRound3, true, 1, ""},
+				{"initiator", TransitionEscalateToRound2, false, 1, TransitionEscalateToRound2},
+				{"target", TransitionEscalateToRound2, false, 2, ""},
+				{"target", TransitionEscalateToRound2, true, 2, ""},
+				{"target", TransitionEscalateToRound3, false, 2, TransitionEscalateToRound3},
+				{"initiator", TransitionEscalateToRound3, false, 3, ""},
+			},
+			[]string{"escalate_to_round2 1->1", "escalate_to_round2 1->2", "escalate_to_round3 2->2", "escalate_to_round3 2->3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database := openTestDB(t)
+			insertTestParticipants(t, database)
+			insertTestConversation(t, database, "conv", StateActive)
+
+			for i, s := range tt.steps {
+				status, err := ApplyRoundTransition(database, "conv", s.agent, s.transition)
+				if (err != nil) != s.wantErr {
+					t.Fatalf("step %d (%s by %s): err = %v, wantErr %v", i, s.transition, s.agent, err, s.wantErr)
+				}
+				var round int
+				var pending string
+				if err := database.QueryRow("SELECT round, pending_transition FROM conversations WHERE id = 'conv'").Scan(&round, &pending); err != nil {
+					t.Fatal(err)
+				}
+				if round != s.wantRound || pending != s.wantPending {
+					t.Fatalf("step %d: round, pending = %d, %q; want %d, %q", i, round, pending, s.wantRound, s.wantPending)
+				}
+				if err == nil && (status.Round != round || status.PendingTransition != pending) {
+					t.Errorf("step %d: status = %+v, stored %d, %q", i, status, round, pending)
+				}
+			}
+			if history := conversationHistory(t, database, "conv"); !equalIDs(history, tt.wantHistory) {
+				t.Errorf("history = %v, want %v", history, tt.wantHistory)
+			}
+		})
+	}
+}
+
+func TestApplyRoundTransitionRejectsLifecycleEvents(t *testing.T) {
+	database := openTestDB(t)
+	insertTestParticipants(t, database)
+	insertTestConversation(t, database, "conv", StatePendingAcceptance)
+
+	if _, err := ApplyRoundTransition(database, "conv", "target", EventAccept); !errors.Is(err, ErrUnknownTransition) {
+		t.Errorf("err = %v, want ErrUnknownTransition", err)
+	}
+}
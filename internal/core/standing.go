@@ -0,0 +1,209 @@
+package core
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"agentsocial/internal/config"
+)
+
+// standingSearch is an active radar task registered as a standing query.
+type standingSearch struct {
+	taskID   string
+	agentID  string
+	taskType string
+	keywords []string
+	filters  map[string]interface{}
+	location *GeoPoint
+}
+
+// NotifyStandingSearches checks a beacon that was just created or changed against every
+// standing radar search and queues a new_match notification for each radar it matches.
+// A radar is notified only if its own scan would return the beacon: the beacon is run
+// through FindMatches with the radar's stored embedding, keywords, standing filters and
+// location, the scan's type, block, conversation and heartbeat checks, and ScanMinScore.
+// With keywordOnly (no embedding provider), matching is keyword-only as in a scan.
+// Each radar/beacon pair is notified once. Radars whose type the compatibility matrix
+// does not pair with the beacon's are skipped without running their scan.
+// Returns the number of notifications queued.
+func NotifyStandingSearches(db *sql.DB, index *VectorIndex, cfg *config.Config, taskID string, keywordOnly bool) (int, error) {
+	var agentID, mode, taskType, status string
+	err := db.QueryRow(
+		"SELECT agent_id, mode, type, status FROM tasks WHERE id = ?",
+		taskID,
+	).Scan(&agentID, &mode, &taskType, &status)
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up task: %w", err)
+	}
+	if mode != "beacon" || status != "active" {
+		return 0, nil
+	}
+
+	searches, err := compatibleStandingSearches(db, cfg, agentID, taskType)
+	if err != nil {
+		return 0, err
+	}
+
+	queued := 0
+	for _, s := range searches {
+		n, err := s.notify(db, index, cfg, keywordOnly, []string{taskID}, 1)
+		queued += n
+		if err != nil {
+			return queued, err
+		}
+	}
+	return queued, nil
+}
+
+// BackfillStandingSearch queues new_match notifications for the beacons that already
+// match a radar task, as its scan would return them (up to ScanMaxResults). It is
+// called when a radar becomes a standing search, or when a standing search changes,
+// so that beacons created earlier are not missed. Tasks that are not active standing
+// radars are skipped. Returns the number of notifications queued.
+func BackfillStandingSearch(db *sql.DB, index *VectorIndex, cfg *config.Config, taskID string, keywordOnly bool) (int, error) {
+	searches, err := loadStandingSearches(db, "t.id = ?", taskID)
+	if err != nil || len(searches) == 0 {
+		return 0, err
+	}
+	return searches[0].notify(db, index, cfg, keywordOnly, nil, cfg.ScanMaxResults)
+}
+
+// notify runs the radar's scan, restricted to onlyTaskIDs if set, and queues a
+// new_match notification for up to maxResults of the results.
+func (s standingSearch) notify(db *sql.DB, index *VectorIndex, cfg *config.Config, keywordOnly bool, onlyTaskIDs []string, maxResults int) (int, error) {
+	var queryEmbedding []float32
+	if !keywordOnly {
+		var ok bool
+		queryEmbedding, ok = index.Vector(s.taskID)
+		if !ok {
+			// Not embedded yet (or awaiting re-embedding); the radar cannot be
+			// scored until it is.
+			return 0, nil
+		}
+	}
+
+	opts, err := s.matchOptions(db, cfg)
+	if err != nil {
+		return 0, err
+	}
+	opts.OnlyTaskIDs = onlyTaskIDs
+	opts.MaxResults = maxResults
+
+	matches, _, err := FindMatches(db, index, queryEmbedding, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	queued := 0
+	for _, m := range matches {
+		result, err := db.Exec(
+			`INSERT OR IGNORE INTO match_notifications (id, agent_id, task_id, match_agent_id, match_task_id, score, created_at)
+			 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			GenerateMD5(s.taskID, m.TaskID), s.agentID, s.taskID, m.AgentID, m.TaskID, m.Score, now,
+		)
+		if err != nil {
+			return queued, fmt.Errorf("failed to queue match notification: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			queued++
+		}
+	}
+	return queued, nil
+}
+
+// matchOptions returns the options the radar's own scan runs with.
+func (s standingSearch) matchOptions(db *sql.DB, cfg *config.Config) (MatchOptions, error) {
+	contacted, err := ContactedTasks(db, s.taskID)
+	if err != nil {
+		return MatchOptions{}, err
+	}
+	blocked, err := BlockedAgents(db, s.agentID)
+	if err != nil {
+		return MatchOptions{}, err
+	}
+
+	heartbeatCutoff := ""
+	if cfg.AgentInactiveDays > 0 {
+		heartbeatCutoff = time.Now().UTC().AddDate(0, 0, -cfg.AgentInactiveDays).Format(time.RFC3339)
+	}
+	reciprocalTaskID := ""
+	if cfg.ScanReciprocal {
+		reciprocalTaskID = s.taskID
+	}
+
+	return MatchOptions{
+		Mode:             "beacon",
+		TypeRules:        CompatibleTaskTypes(cfg.TaskTypeCompatibility, s.taskType),
+		ExcludeAgentIDs:  append(blocked, s.agentID),
+		Contacted:        contacted,
+		MinScore:         cfg.ScanMinScore,
+		HeartbeatCutoff:  heartbeatCutoff,
+		Keywords:         s.keywords,
+		LexicalWeight:    cfg.LexicalWeight,
+		Filters:          s.filters,
+		Origin:           s.location,
+		ReciprocalTaskID: reciprocalTaskID,
+		MaxPerAgent:      cfg.ScanMaxPerAgent,
+		Diversity:        cfg.ScanDiversity,
+	}, nil
+}
+
+// compatibleStandingSearches returns the standing searches of other agents whose type
+// may be matched with a beacon of beaconType. Compatibility rules are symmetric, so
+// these are the types the beacon's own type is compatible with.
+func compatibleStandingSearches(db *sql.DB, cfg *config.Config, beaconAgentID, beaconType string) ([]standingSearch, error) {
+	allowed := CompatibleTaskTypes(cfg.TaskTypeCompatibility, beaconType)
+	if len(allowed) == 0 {
+		return nil, nil
+	}
+	searches, err := loadStandingSearches(db, "t.agent_id != ?", beaconAgentID)
+	if err != nil {
+		return nil, err
+	}
+	compatible := searches[:0]
+	for _, s := range searches {
+		if _, ok := typeRule(allowed, s.taskType); ok {
+			compatible = append(compatible, s)
+		}
+	}
+	return compatible, nil
+}
+
+// loadStandingSearches returns the active standing radar searches of active agents
+// that satisfy the extra condition on the tasks table (aliased t).
+func loadStandingSearches(db *sql.DB, condition string, args ...interface{}) ([]standingSearch, error) {
+	rows, err := db.Query(
+		`SELECT t.id, t.agent_id, t.type, t.keywords, t.standing_filters, t.latitude, t.longitude, t.radius_km
+		 FROM tasks t
+		 JOIN agents a ON a.id = t.agent_id
+		 WHERE t.standing = 1
+		   AND t.mode = 'radar'
+		   AND t.status = 'active'
+		   AND a.status = 'active'
+		   AND `+condition,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query standing searches: %w", err)
+	}
+	defer rows.Close()
+
+	var searches []standingSearch
+	for rows.Next() {
+		var s standingSearch
+		var keywordsJSON, filtersJSON string
+		var lat, lon sql.NullFloat64
+		var radius float64
+		if err := rows.Scan(&s.taskID, &s.agentID, &s.taskType, &keywordsJSON, &filtersJSON, &lat, &lon, &radius); err != nil {
+			return nil, fmt.Errorf("failed to scan standing search: %w", err)
+		}
+		s.location = taskGeoPoint(lat, lon, radius)
+		_ = json.Unmarshal([]byte(keywordsJSON), &s.keywords)
+		_ = json.Unmarshal([]byte(filtersJSON), &s.filters)
+		searches = append(searches, s)
+	}
+	return searches, rows.Err()
+}
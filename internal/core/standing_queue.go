@@ -0,0 +1,111 @@
+package core
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"agentsocial/internal/config"
+)
+
+// Standing match jobs, run by the standing match worker off the request path.
+const (
+	standingJobMatch    = "match"    // match a new or changed beacon against standing searches
+	standingJobBackfill = "backfill" // notify a standing search of beacons that already match it
+)
+
+const standingMatchBatchSize = 50
+
+// QueueStandingMatches queues tasks that were created or changed to be matched
+// against the standing searches; tasks that are not active beacons are skipped when
+// the job runs. Queueing a task again before its job runs is a no-op.
+func QueueStandingMatches(db *sql.DB, taskIDs []string) {
+	queueStandingJobs(db, standingJobMatch, taskIDs)
+}
+
+// QueueStandingBackfills queues standing radar searches to be notified of the
+// beacons that already match them (see BackfillStandingSearch).
+func QueueStandingBackfills(db *sql.DB, taskIDs []string) {
+	queueStandingJobs(db, standingJobBackfill, taskIDs)
+}
+
+func queueStandingJobs(db *sql.DB, job string, taskIDs []string) {
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	for _, taskID := range taskIDs {
+		_, err := db.Exec(
+			"INSERT OR IGNORE INTO standing_match_queue (task_id, job, created_at) VALUES (?, ?, ?)",
+			taskID, job, now,
+		)
+		if err != nil {
+			log.Printf("WARNING: failed to queue standing %s for task %s: %v", job, taskID, err)
+		}
+	}
+}
+
+// StartStandingMatchWorker periodically runs queued standing match jobs, so that
+// scanning every standing search for a changed beacon does not hold up the request
+// that changed it.
+func StartStandingMatchWorker(db *sql.DB, embClient Embedder, index *VectorIndex, cfg *config.Config) {
+	if cfg.StandingMatchIntervalSeconds <= 0 {
+		return
+	}
+	_, keywordOnly := embClient.(NoopEmbedder)
+
+	ticker := time.NewTicker(time.Duration(cfg.StandingMatchIntervalSeconds) * time.Second)
+	for range ticker.C {
+		for {
+			jobs, queued := runStandingMatchJobs(db, index, cfg, keywordOnly)
+			if queued > 0 {
+				log.Printf("Standing searches: %d new match notifications", queued)
+			}
+			if jobs < standingMatchBatchSize {
+				break
+			}
+		}
+	}
+}
+
+// runStandingMatchJobs runs up to standingMatchBatchSize queued jobs, oldest first.
+// A job is removed before it runs, so a task changed again meanwhile is queued anew;
+// failures are logged and not retried. Returns the number of jobs run and of
+// notifications queued.
+func runStandingMatchJobs(db *sql.DB, index *VectorIndex, cfg *config.Config, keywordOnly bool) (int, int) {
+	rows, err := db.Query(
+		"SELECT task_id, job FROM standing_match_queue ORDER BY created_at ASC LIMIT ?",
+		standingMatchBatchSize,
+	)
+	if err != nil {
+		log.Printf("Standing match error (query queue): %v", err)
+		return 0, 0
+	}
+	var jobs [][2]string
+	for rows.Next() {
+		var taskID, job string
+		if err := rows.Scan(&taskID, &job); err != nil {
+			continue
+		}
+		jobs = append(jobs, [2]string{taskID, job})
+	}
+	rows.Close()
+
+	queued := 0
+	for _, j := range jobs {
+		taskID, job := j[0], j[1]
+		if _, err := db.Exec("DELETE FROM standing_match_queue WHERE task_id = ? AND job = ?", taskID, job); err != nil {
+			log.Printf("Standing match error (dequeue task %s): %v", taskID, err)
+			continue
+		}
+
+		var n int
+		if job == standingJobBackfill {
+			n, err = BackfillStandingSearch(db, index, cfg, taskID, keywordOnly)
+		} else {
+			n, err = NotifyStandingSearches(db, index, cfg, taskID, keywordOnly)
+		}
+		queued += n
+		if err != nil {
+			log.Printf("Standing match error (%s task %s): %v", job, taskID, err)
+		}
+	}
+	return len(jobs), queued
+}
@@ -0,0 +1,75 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestRunStandingMatchJobs(t *testing.T) {
+	database := openTestDB(t)
+	index := NewVectorIndex("m", 2)
+	insertTestAgent(t, database, "seeker")
+	insertTestAgent(t, database, "company")
+	addStandingRadar(t, database, index, "radar", "seeker", `["go"]`, `{}`)
+	addTestBeacon(t, database, index, "beacon", "company", `["go"]`, unitAt(0.9))
+
+	QueueStandingMatches(database, []string{"beacon", "beacon"})
+	QueueStandingBackfills(database, []string{"radar"})
+	if n := notificationCount(t, database, "radar"); n != 0 {
+		t.Fatalf("%d notifications before the worker ran", n)
+	}
+
+	jobs, queued := runStandingMatchJobs(database, index, standingTestConfig(), false)
+	if jobs != 2 || queued != 1 {
+		t.Errorf("jobs, queued = %d, %d; want 2, 1 (one job per task and kind, one notification per pair)", jobs, queued)
+	}
+	if n := notificationCount(t, database, "radar"); n != 1 {
+		t.Errorf("notifications = %d, want 1", n)
+	}
+	var left int
+	if err := database.QueryRow("SELECT COUNT(*) FROM standing_match_queue").Scan(&left); err != nil {
+		t.Fatal(err)
+	}
+	if left != 0 {
+		t.Errorf("%d jobs left in the queue", left)
+	}
+}
+
+func TestCompatibleStandingSearches(t *testing.T) {
+	database := openTestDB(t)
+	index := NewVectorIndex("m", 2)
+	insertTestAgent(t, database, "seeker")
+	insertTestAgent(t, database, "dater")
+	insertTestAgent(t, database, "company")
+	addStandingRadar(t, database, index, "job-radar", "seeker", `["go"]`, `{}`)
+	addStandingRadar(t, database, index, "dating-radar", "dater", `["go"]`, `{}`)
+	addStandingRadar(t, database, index, "own-radar", "company", `["go"]`, `{}`)
+	if _, err := database.Exec("UPDATE tasks SET type = 'dating' WHERE id = 'dating-radar'"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		beaconType string
+		want       []string
+	}{
+		{"hiring", []string{"job-radar"}},
+		{"Hiring", []string{"job-radar"}},
+		{"networking", nil},
+		// dating has no rule, so it matches its own type.
+		{"dating", []string{"dating-radar"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.beaconType, func(t *testing.T) {
+			searches, err := compatibleStandingSearches(database, standingTestConfig(), "company", tt.beaconType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, s := range searches {
+				got = append(got, s.taskID)
+			}
+			if !equalIDs(got, tt.want) {
+				t.Errorf("searches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package core
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"agentsocial/internal/config"
+)
+
+func standingTestConfig() *config.Config {
+	return &config.Config{
+		ScanMaxResults:        10,
+		ScanMinScore:          0.7,
+		LexicalWeight:         0.3,
+		AgentInactiveDays:     7,
+		TaskTypeCompatibility: [][2]string{{"job-seeking", "hiring"}},
+	}
+}
+
+// addStandingRadar inserts an active standing radar indexed at (1, 0).
+func addStandingRadar(t *testing.T, database *sql.DB, index *VectorIndex, id, agentID, keywordsJSON, filtersJSON string) {
+	t.Helper()
+	insertTestTask(t, database, id, agentID, "radar", "job-seeking", id, keywordsJSON)
+	if _, err := database.Exec("UPDATE tasks SET standing = 1, standing_filters = ? WHERE id = ?", filtersJSON, id); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveTaskEmbedding(database, index, id, []float32{1, 0}, index.model); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func notificationCount(t *testing.T, database *sql.DB, radarID string) int {
+	t.Helper()
+	var n int
+	if err := database.QueryRow("SELECT COUNT(*) FROM match_notifications WHERE task_id = ?", radarID).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestNotifyStandingSearchesMatchesLikeAScan(t *testing.T) {
+	tests := []struct {
+		name         string
+		radarFilters string
+		beaconKw     string
+		beaconAttrs  string
+		beaconVec    []float32
+		staleAgent   bool
+		want         int
+	}{
+		{"semantic match", `{}`, `["go"]`, `{}`, unitAt(0.9), false, 1},
+		{"below min score", `{}`, `["go"]`, `{}`, unitAt(0.5), false, 0},
+		// 0.7*0.6 + 0.3*1 reaches 0.7 through the radar's keyword.
+		{"keyword lifts near miss", `{}`, `["rust"]`, `{}`, unitAt(0.6), false, 1},
+		{"standing filter met", `{"remote": true}`, `["go"]`, `{"remote": true}`, unitAt(0.9), false, 1},
+		{"standing filter not met", `{"remote": true}`, `["go"]`, `{"remote": false}`, unitAt(0.9), false, 0},
+		{"inactive agent", `{}`, `["go"]`, `{}`, unitAt(0.9), true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database := openTestDB(t)
+			index := NewVectorIndex("m", 2)
+			insertTestAgent(t, database, "seeker")
+			insertTestAgent(t, database, "company")
+			addStandingRadar(t, database, index, "radar", "seeker", `["rust"]`, tt.radarFilters)
+			addTestBeacon(t, database, index, "beacon", "company", tt.beaconKw, tt.beaconVec)
+			setTestAttributes(t, database, index, "beacon", tt.beaconAttrs)
+			if tt.staleAgent {
+				old := time.Now().UTC().AddDate(0, 0, -30).Format(time.RFC3339)
+				if _, err := database.Exec("UPDATE agents SET last_heartbeat = ? WHERE id = 'company'", old); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			queued, err := NotifyStandingSearches(database, index, standingTestConfig(), "beacon", false)
+			if err != nil {
+				t.Fatalf("NotifyStandingSearches: %v", err)
+			}
+			if queued != tt.want || notificationCount(t, database, "radar") != tt.want {
+				t.Errorf("queued = %d, want %d", queued, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotifyStandingSearchesOncePerPair(t *testing.T) {
+	database := openTestDB(t)
+	index := NewVectorIndex("m", 2)
+	insertTestAgent(t, database, "seeker")
+	insertTestAgent(t, database, "company")
+	addStandingRadar(t, database, index, "radar", "seeker", `["go"]`, `{}`)
+	addTestBeacon(t, database, index, "beacon", "company", `["go"]`, unitAt(0.9))
+
+	for i, want := range []int{1, 0} {
+		queued, err := NotifyStandingSearches(database, index, standingTestConfig(), "beacon", false)
+		if err != nil || queued != want {
+			t.Errorf("call %d: queued = %d, %v; want %d", i, queued, err, want)
+		}
+	}
+}
+
+func TestBackfillStandingSearch(t *testing.T) {
+	database := openTestDB(t)
+	index := NewVectorIndex("m", 2)
+	cfg := standingTestConfig()
+	insertTestAgent(t, database, "seeker")
+	insertTestAgent(t, database, "company")
+	addTestBeacon(t, database, index, "close", "company", `["go"]`, unitAt(0.9))
+	addTestBeacon(t, database, index, "closer", "company", `["go"]`, unitAt(0.95))
+	addTestBeacon(t, database, index, "far", "company", `["go"]`, unitAt(0.2))
+	addStandingRadar(t, database, index, "radar", "seeker", `["go"]`, `{}`)
+
+	if _, err := database.Exec("UPDATE tasks SET standing = 0 WHERE id = 'radar'"); err != nil {
+		t.Fatal(err)
+	}
+	if queued, err := BackfillStandingSearch(database, index, cfg, "radar", false); err != nil || queued != 0 {
+		t.Fatalf("non-standing radar: queued = %d, %v; want 0", queued, err)
+	}
+
+	if _, err := database.Exec("UPDATE tasks SET standing = 1 WHERE id = 'radar'"); err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []int{2, 0} {
+		queued, err := BackfillStandingSearch(database, index, cfg, "radar", false)
+		if err != nil || queued != want {
+			t.Errorf("call %d: queued = %d, %v; want %d", i, queued, err, want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"agentsocial/internal/config"
+)
+
+// SaveTaskEmbedding stores a task's embedding together with the model that produced
+// it and its length, replacing any previous vector, and updates the index.
+func SaveTaskEmbedding(db *sql.DB, index *VectorIndex, taskID string, embedding []float32, model string) error {
+	_, err := db.Exec(
+		"INSERT OR REPLACE INTO task_embeddings (task_id, embedding, model, dimensions) VALUES (?, ?, ?, ?)",
+		taskID, EmbeddingToBytes(embedding), model, len(embedding),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store embedding: %w", err)
+	}
+	return index.Refresh(db, taskID)
+}
+
+// DeleteTaskEmbedding removes a task from matching: its stored embedding,
+// its index entry and any pending retry.
+func DeleteTaskEmbedding(db *sql.DB, index *VectorIndex, taskID string) {
+	_, _ = db.Exec("DELETE FROM task_embeddings WHERE task_id = ?", taskID)
+	index.Remove(taskID)
+	DequeueTaskEmbeddings(db, []string{taskID})
+}
+
+// KeywordsText joins a task's stored JSON keyword array into embedding input.
+func KeywordsText(keywordsJSON string) string {
+	var keywords []string
+	_ = json.Unmarshal([]byte(keywordsJSON), &keywords)
+	return strings.Join(keywords, " ")
+}
+
+// StartEmbeddingMigrator periodically re-embeds active tasks' embeddings produced
+// by a different model or dimension count than the current embedder (or whose
+// stored vector does not have the recorded dimension count), at most
+// batchSize tasks per interval, so a model change does not hammer the provider.
+// Inactive tasks are migrated once they become active again.
+func StartEmbeddingMigrator(db *sql.DB, embClient Embedder, index *VectorIndex, cfg *config.Config) {
+	if cfg.EmbeddingMigrationBatchSize <= 0 || cfg.EmbeddingMigrationIntervalSeconds <= 0 {
+		return
+	}
+	if _, ok := embClient.(NoopEmbedder); ok {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(cfg.EmbeddingMigrationIntervalSeconds) * time.Second)
+	for range ticker.C {
+		migrated, remaining, err := migrateStaleEmbeddings(db, embClient, index, cfg.EmbeddingMigrationBatchSize)
+		if err != nil {
+			log.Printf("Embedding migration error: %v", err)
+			continue
+		}
+		if migrated > 0 {
+			log.Printf("Embedding migration: re-embedded %d tasks, %d remaining", migrated, remaining)
+		}
+	}
+}
+
+// staleEmbeddingCondition matches task_embeddings rows (te) that the current model
+// and dimension count (bound in that order, dimensions twice) cannot search. Blobs
+// are 4 bytes per float32.
+const staleEmbeddingCondition = "(te.model != ? OR te.dimensions != ? OR length(te.embedding) != ? * 4)"
+
+// migrateStaleEmbeddings re-embeds up to limit stale rows of active tasks in one
+// batch call. Returns how many were migrated and how many stale rows remain.
+func migrateStaleEmbeddings(db *sql.DB, embClient Embedder, index *VectorIndex, limit int) (int, int, error) {
+	model, dimensions := embClient.Model(), embClient.Dimensions()
+
+	rows, err := db.Query(
+		`SELECT te.task_id, t.keywords
+		 FROM task_embeddings te
+		 JOIN tasks t ON t.id = te.task_id
+		 WHERE `+staleEmbeddingCondition+`
+		   AND t.status = 'active'
+		   AND t.keywords NOT IN ('', '[]', 'null')
+		 LIMIT ?`,
+		model, dimensions, dimensions, limit,
+	)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to query stale embeddings: %w", err)
+	}
+
+	var taskIDs, texts []string
+	for rows.Next() {
+		var taskID, keywords string
+		if err := rows.Scan(&taskID, &keywords); err != nil {
+			continue
+		}
+		taskIDs = append(taskIDs, taskID)
+		texts = append(texts, KeywordsText(keywords))
+	}
+	rows.Close()
+
+	if len(taskIDs) == 0 {
+		return 0, 0, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	embeddings, err := embClient.GetEmbeddings(ctx, texts)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	migrated := 0
+	for i, embedding := range embeddings {
+		if err := SaveTaskEmbedding(db, index, taskIDs[i], embedding, model); err != nil {
+			log.Printf("Embedding migration error (task %s): %v", taskIDs[i], err)
+			continue
+		}
+		migrated++
+	}
+
+	var remaining int
+	_ = db.QueryRow(
+		`SELECT COUNT(*)
+		 FROM task_embeddings te
+		 JOIN tasks t ON t.id = te.task_id
+		 WHERE `+staleEmbeddingCondition+`
+		   AND t.status = 'active'
+		   AND t.keywords NOT IN ('', '[]', 'null')`,
+		model, dimensions, dimensions,
+	).Scan(&remaining)
+
+	return migrated, remaining, nil
+}
@@ -0,0 +1,84 @@
+package core
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestMigrateStaleEmbeddingsOnlyActiveTasks(t *testing.T) {
+	database := openTestDB(t)
+	insertTestAgent(t, database, "agent")
+	for _, id := range []string{"current", "stale", "stale-paused", "stale-completed"} {
+		insertTestTask(t, database, id, "agent", "beacon", "general", id, `["go"]`)
+	}
+	for _, id := range []string{"stale-paused", "stale-completed"} {
+		status := id[len("stale-"):]
+		if _, err := database.Exec("UPDATE tasks SET status = ? WHERE id = ?", status, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	old := &fakeEmbedder{model: "old", dimensions: 2}
+	emb := &fakeEmbedder{model: "new", dimensions: 3}
+	index := NewVectorIndex(emb.Model(), emb.Dimensions())
+	mustSave(t, database, index, "current", []float32{1, 0, 0}, emb)
+	for _, id := range []string{"stale", "stale-paused", "stale-completed"} {
+		mustSave(t, database, index, id, []float32{1, 0}, old)
+	}
+
+	migrated, remaining, err := migrateStaleEmbeddings(database, emb, index, 10)
+	if err != nil {
+		t.Fatalf("migrateStaleEmbeddings: %v", err)
+	}
+	if migrated != 1 || remaining != 0 {
+		t.Errorf("migrated, remaining = %d, %d; want 1, 0", migrated, remaining)
+	}
+	if len(emb.calls) != 1 || len(emb.calls[0]) != 1 {
+		t.Errorf("provider calls = %q, want one call for the active task", emb.calls)
+	}
+	if _, ok := index.Vector("stale"); !ok {
+		t.Error("active stale task not searchable after migration")
+	}
+	if index.Incompatible() != 2 {
+		t.Errorf("incompatible = %d, want the 2 inactive tasks", index.Incompatible())
+	}
+}
+
+func mustSave(t *testing.T, database *sql.DB, index *VectorIndex, taskID string, vec []float32, emb Embedder) {
+	t.Helper()
+	if err := SaveTaskEmbedding(database, index, taskID, vec, emb.Model()); err != nil {
+		t.Fatalf("SaveTaskEmbedding(%s): %v", taskID, err)
+	}
+}
+
+func TestMigrateStaleEmbeddingsRepairsMislabelledRows(t *testing.T) {
+	database := openTestDB(t)
+	insertTestAgent(t, database, "agent")
+	insertTestTask(t, database, "task", "agent", "beacon", "general", "t", `["go"]`)
+
+	// Written before vector lengths were recorded: labelled 3 dimensions, holds 4.
+	_, err := database.Exec(
+		"INSERT INTO task_embeddings (task_id, embedding, model, dimensions) VALUES ('task', ?, 'm', 3)",
+		EmbeddingToBytes([]float32{1, 0, 0, 0}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	emb := &fakeEmbedder{model: "m", dimensions: 3}
+	index := NewVectorIndex(emb.Model(), emb.Dimensions())
+	if err := index.Load(database); err != nil {
+		t.Fatal(err)
+	}
+	if index.Incompatible() != 1 {
+		t.Fatalf("Incompatible = %d, want 1", index.Incompatible())
+	}
+
+	migrated, remaining, err := migrateStaleEmbeddings(database, emb, index, 10)
+	if err != nil {
+		t.Fatalf("migrateStaleEmbeddings: %v", err)
+	}
+	if migrated != 1 || remaining != 0 || index.Len() != 1 {
+		t.Errorf("migrated, remaining, Len = %d, %d, %d; want 1, 0, 1", migrated, remaining, index.Len())
+	}
+}
@@ -0,0 +1,54 @@
+package core
+
+import "strings"
+
+// anyTaskType in a compatibility rule matches every task type.
+const anyTaskType = "*"
+
+// CompatibleTaskTypes resolves which task types a task of taskType may be matched
+// with under the given symmetric rules. It returns a map from candidate type to the
+// rule that allows it, formatted as "a/b". A "*" key means any type is allowed.
+// Types are compared case-insensitively. A type that no rule names, directly or
+// through "*", matches tasks of its own type, so a new type is not left without
+// matches until it is configured.
+func CompatibleTaskTypes(rules [][2]string, taskType string) map[string]string {
+	taskType = normalizeTaskType(taskType)
+	allowed := make(map[string]string)
+	listed := false
+
+	for _, r := range rules {
+		a, b := normalizeTaskType(r[0]), normalizeTaskType(r[1])
+		label := a + "/" + b
+		if a == taskType || b == taskType || a == anyTaskType || b == anyTaskType {
+			listed = true
+		}
+		for _, side := range [][2]string{{a, b}, {b, a}} {
+			if side[0] != taskType && side[0] != anyTaskType {
+				continue
+			}
+			if _, ok := allowed[side[1]]; !ok {
+				allowed[side[1]] = label
+			}
+		}
+	}
+
+	if !listed && taskType != "" {
+		if _, ok := allowed[taskType]; !ok {
+			allowed[taskType] = taskType + "/" + taskType
+		}
+	}
+	return allowed
+}
+
+// typeRule returns the rule that allows candidateType, or false if none does.
+func typeRule(allowed map[string]string, candidateType string) (string, bool) {
+	if rule, ok := allowed[normalizeTaskType(candidateType)]; ok {
+		return rule, true
+	}
+	rule, ok := allowed[anyTaskType]
+	return rule, ok
+}
+
+func normalizeTaskType(t string) string {
+	return strings.ToLower(strings.TrimSpace(t))
+}
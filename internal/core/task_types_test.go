@@ -0,0 +1,94 @@
+package core
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"agentsocial/internal/config"
+)
+
+// allowedTypes renders CompatibleTaskTypes as sorted "type=rule" entries.
+func allowedTypes(rules [][2]string, taskType string) string {
+	var entries []string
+	for t, rule := range CompatibleTaskTypes(rules, taskType) {
+		entries = append(entries, t+"="+rule)
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, " ")
+}
+
+func TestCompatibleTaskTypes(t *testing.T) {
+	t.Setenv("TASK_TYPE_COMPATIBILITY", "")
+	defaults := config.Load().TaskTypeCompatibility
+
+	tests := []struct {
+		name     string
+		rules    [][2]string
+		taskType string
+		want     string
+	}{
+		{"hiring", defaults, "hiring", "job-seeking=hiring/job-seeking"},
+		{"job-seeking", defaults, "job-seeking", "hiring=hiring/job-seeking"},
+		{"dating", defaults, "dating", "dating=dating/dating"},
+		{"partnership", defaults, "partnership", "partnership=partnership/partnership"},
+		{"networking", defaults, "networking", "networking=networking/networking"},
+		{"other", defaults, "other", "other=other/other"},
+		{"case and spacing", defaults, " Hiring ", "job-seeking=hiring/job-seeking"},
+		{"unknown type matches itself", defaults, "mentoring", "mentoring=mentoring/mentoring"},
+		{"empty type", defaults, "", ""},
+		{"wildcard", [][2]string{{"*", "networking"}}, "hiring", "networking=*/networking"},
+		{"wildcard both ways", [][2]string{{"*", "networking"}}, "networking", "*=*/networking networking=*/networking"},
+		{"first rule wins", [][2]string{{"a", "b"}, {"b", "a"}}, "a", "b=a/b"},
+		{"no rules", nil, "hiring", "hiring=hiring/hiring"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := allowedTypes(tt.rules, tt.taskType); got != tt.want {
+				t.Errorf("CompatibleTaskTypes(%q) = %q, want %q", tt.taskType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompatibleTaskTypesMalformedConfig(t *testing.T) {
+	t.Setenv("TASK_TYPE_COMPATIBILITY", "hiring:job-seeking, broken ,:x,dating:,a:b:c")
+	rules := config.Load().TaskTypeCompatibility
+
+	tests := []struct {
+		taskType string
+		want     string
+	}{
+		{"hiring", "job-seeking=hiring/job-seeking"},
+		{"broken", "broken=broken/broken"},
+		{"dating", "dating=dating/dating"},
+		// "a:b:c" splits on the first colon.
+		{"a", "b:c=a/b:c"},
+	}
+	for _, tt := range tests {
+		if got := allowedTypes(rules, tt.taskType); got != tt.want {
+			t.Errorf("CompatibleTaskTypes(%q) = %q, want %q", tt.taskType, got, tt.want)
+		}
+	}
+}
+
+func TestTypeRule(t *testing.T) {
+	tests := []struct {
+		allowed   map[string]string
+		candidate string
+		want      string
+		wantOK    bool
+	}{
+		{map[string]string{"job-seeking": "hiring/job-seeking"}, "Job-Seeking", "hiring/job-seeking", true},
+		{map[string]string{"job-seeking": "hiring/job-seeking"}, "dating", "", false},
+		{map[string]string{"*": "*/networking"}, "dating", "*/networking", true},
+		{map[string]string{"dating": "dating/dating", "*": "*/x"}, "dating", "dating/dating", true},
+		{map[string]string{}, "dating", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := typeRule(tt.allowed, tt.candidate)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("typeRule(%v, %q) = %q, %v; want %q, %v", tt.allowed, tt.candidate, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
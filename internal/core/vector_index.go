@@ -0,0 +1,317 @@
+package core
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+)
+
+// IndexedTask is the per-vector metadata kept in the index for cheap filtering.
+type IndexedTask struct {
+	TaskID  string
+	AgentID string
+	Mode    string
+	Type    string
+	// Attributes are the task's parsed structured attributes.
+	Attributes map[string]interface{}
+	// Location is nil if the task has none.
+	Location *GeoPoint
+}
+
+// ScoredTask is a search hit from the vector index.
+type ScoredTask struct {
+	IndexedTask
+	Score float64
+}
+
+// VectorIndex is an in-memory flat index mirroring task_embeddings. Vectors are
+// L2-normalized and stored contiguously, so a query is a single pass of dot
+// products with no disk reads or blob decoding.
+//
+// The index only tracks which vectors exist; agent and task status (paused,
+// hibernated, banned, heartbeat) are checked when hits are hydrated from the
+// database, so status changes never leave the index inconsistent. Task attributes
+// and location are copied in for filtering, so a task whose attributes or location
+// change must be refreshed.
+type VectorIndex struct {
+	mu         sync.RWMutex
+	model      string
+	dimensions int
+	vectors    []float32 // len(tasks) * dimensions
+	tasks      []IndexedTask
+	positions  map[string]int
+	// incompatible holds tasks embedded with another model or dimension count.
+	incompatible map[string]IndexedTask
+}
+
+// NewVectorIndex creates an empty index for vectors of the given model and size.
+func NewVectorIndex(model string, dimensions int) *VectorIndex {
+	return &VectorIndex{
+		model:        model,
+		dimensions:   dimensions,
+		positions:    make(map[string]int),
+		incompatible: make(map[string]IndexedTask),
+	}
+}
+
+// Load replaces the index contents with every stored task embedding.
+func (ix *VectorIndex) Load(db *sql.DB) error {
+	rows, err := db.Query(
+		`SELECT te.task_id, t.agent_id, t.mode, t.type, t.attributes, t.latitude, t.longitude, t.radius_km,
+		        te.embedding, te.model, te.dimensions
+		 FROM task_embeddings te
+		 JOIN tasks t ON t.id = te.task_id`,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to load task embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.vectors = ix.vectors[:0]
+	ix.tasks = ix.tasks[:0]
+	ix.positions = make(map[string]int)
+	ix.incompatible = make(map[string]IndexedTask)
+
+	for rows.Next() {
+		var (
+			task       IndexedTask
+			attrsJSON  string
+			lat, lon   sql.NullFloat64
+			radius     float64
+			raw        []byte
+			model      string
+			dimensions int
+		)
+		if err := rows.Scan(&task.TaskID, &task.AgentID, &task.Mode, &task.Type, &attrsJSON, &lat, &lon, &radius, &raw, &model, &dimensions); err != nil {
+			return fmt.Errorf("failed to scan task embedding: %w", err)
+		}
+		task.Attributes = parseTaskAttributes(attrsJSON)
+		task.Location = taskGeoPoint(lat, lon, radius)
+		ix.upsertLocked(task, BytesToEmbedding(raw), model, dimensions)
+	}
+
+	return rows.Err()
+}
+
+// Refresh reloads a single task's vector and metadata from the database,
+// removing it from the index if it no longer has an embedding.
+func (ix *VectorIndex) Refresh(db *sql.DB, taskID string) error {
+	var (
+		task       IndexedTask
+		attrsJSON  string
+		lat, lon   sql.NullFloat64
+		radius     float64
+		raw        []byte
+		model      string
+		dimensions int
+	)
+	err := db.QueryRow(
+		`SELECT te.task_id, t.agent_id, t.mode, t.type, t.attributes, t.latitude, t.longitude, t.radius_km,
+		        te.embedding, te.model, te.dimensions
+		 FROM task_embeddings te
+		 JOIN tasks t ON t.id = te.task_id
+		 WHERE te.task_id = ?`,
+		taskID,
+	).Scan(&task.TaskID, &task.AgentID, &task.Mode, &task.Type, &attrsJSON, &lat, &lon, &radius, &raw, &model, &dimensions)
+	if err == sql.ErrNoRows {
+		ix.Remove(taskID)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load task embedding: %w", err)
+	}
+	task.Attributes = parseTaskAttributes(attrsJSON)
+	task.Location = taskGeoPoint(lat, lon, radius)
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.upsertLocked(task, BytesToEmbedding(raw), model, dimensions)
+	return nil
+}
+
+// Remove drops a task from the index. Removing an unknown task is a no-op.
+func (ix *VectorIndex) Remove(taskID string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.removeLocked(taskID)
+	delete(ix.incompatible, taskID)
+}
+
+// Len returns the number of searchable vectors.
+func (ix *VectorIndex) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.tasks)
+}
+
+// Incompatible returns how many stored embeddings belong to another model or
+// dimension count and are therefore not searchable until re-embedded.
+func (ix *VectorIndex) Incompatible() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.incompatible)
+}
+
+// IncompatibleTasks returns the tasks with an incompatible embedding that pass
+// filter (all of them if filter is nil): those a search would have considered.
+func (ix *VectorIndex) IncompatibleTasks(filter func(IndexedTask) bool) []string {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	var ids []string
+	for id, task := range ix.incompatible {
+		if filter == nil || filter(task) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Search returns up to k tasks (all if k <= 0) scoring at least minScore
+// against query, best first. filter, if non-nil, is applied before ranking.
+func (ix *VectorIndex) Search(query []float32, k int, minScore float64, filter func(IndexedTask) bool) ([]ScoredTask, error) {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	if len(query) != ix.dimensions {
+		return nil, fmt.Errorf("query has %d dimensions, index has %d", len(query), ix.dimensions)
+	}
+
+	q := normalize(query)
+	var hits []ScoredTask
+	for i, task := range ix.tasks {
+		if filter != nil && !filter(task) {
+			continue
+		}
+		vec := ix.vectors[i*ix.dimensions : (i+1)*ix.dimensions]
+		var dot float64
+		for j, v := range vec {
+			dot += float64(v) * float64(q[j])
+		}
+		if dot >= minScore {
+			hits = append(hits, ScoredTask{IndexedTask: task, Score: dot})
+		}
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		return hits[i].Score > hits[j].Score
+	})
+	if k > 0 && len(hits) > k {
+		hits = hits[:k]
+	}
+	return hits, nil
+}
+
+// Score returns the cosine similarity between query and a task's vector.
+// ok is false if the task is not in the index or the query has the wrong size.
+func (ix *VectorIndex) Score(query []float32, taskID string) (score float64, ok bool) {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	pos, found := ix.positions[taskID]
+	if !found || len(query) != ix.dimensions {
+		return 0, false
+	}
+
+	q := normalize(query)
+	vec := ix.vectors[pos*ix.dimensions : (pos+1)*ix.dimensions]
+	for j, v := range vec {
+		score += float64(v) * float64(q[j])
+	}
+	return score, true
+}
+
+// Similarity returns the cosine similarity between two indexed tasks.
+// ok is false if either task is not in the index.
+func (ix *VectorIndex) Similarity(taskA, taskB string) (score float64, ok bool) {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	posA, foundA := ix.positions[taskA]
+	posB, foundB := ix.positions[taskB]
+	if !foundA || !foundB {
+		return 0, false
+	}
+
+	a := ix.vectors[posA*ix.dimensions : (posA+1)*ix.dimensions]
+	b := ix.vectors[posB*ix.dimensions : (posB+1)*ix.dimensions]
+	for j := range a {
+		score += float64(a[j]) * float64(b[j])
+	}
+	return score, true
+}
+
+// Vector returns a copy of a task's normalized vector.
+// ok is false if the task is not in the index.
+func (ix *VectorIndex) Vector(taskID string) (vec []float32, ok bool) {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	pos, found := ix.positions[taskID]
+	if !found {
+		return nil, false
+	}
+	vec = make([]float32, ix.dimensions)
+	copy(vec, ix.vectors[pos*ix.dimensions:(pos+1)*ix.dimensions])
+	return vec, true
+}
+
+func (ix *VectorIndex) upsertLocked(task IndexedTask, vec []float32, model string, dimensions int) {
+	if model != ix.model || dimensions != ix.dimensions || len(vec) != ix.dimensions {
+		ix.removeLocked(task.TaskID)
+		ix.incompatible[task.TaskID] = task
+		return
+	}
+	delete(ix.incompatible, task.TaskID)
+
+	vec = normalize(vec)
+	if pos, ok := ix.positions[task.TaskID]; ok {
+		ix.tasks[pos] = task
+		copy(ix.vectors[pos*ix.dimensions:], vec)
+		return
+	}
+
+	ix.positions[task.TaskID] = len(ix.tasks)
+	ix.tasks = append(ix.tasks, task)
+	ix.vectors = append(ix.vectors, vec...)
+}
+
+// removeLocked deletes a vector by moving the last one into its slot.
+func (ix *VectorIndex) removeLocked(taskID string) {
+	pos, ok := ix.positions[taskID]
+	if !ok {
+		return
+	}
+
+	last := len(ix.tasks) - 1
+	if pos != last {
+		ix.tasks[pos] = ix.tasks[last]
+		copy(ix.vectors[pos*ix.dimensions:(pos+1)*ix.dimensions], ix.vectors[last*ix.dimensions:])
+		ix.positions[ix.tasks[pos].TaskID] = pos
+	}
+
+	ix.tasks = ix.tasks[:last]
+	ix.vectors = ix.vectors[:last*ix.dimensions]
+	delete(ix.positions, taskID)
+}
+
+// normalize returns a unit-length copy of vec (all zeros if vec is zero).
+func normalize(vec []float32) []float32 {
+	var norm float64
+	for _, v := range vec {
+		norm += float64(v) * float64(v)
+	}
+	out := make([]float32, len(vec))
+	if norm == 0 {
+		return out
+	}
+	norm = math.Sqrt(norm)
+	for i, v := range vec {
+		out[i] = float32(float64(v) / norm)
+	}
+	return out
+}
@@ -0,0 +1,142 @@
+package core
+
+import (
+	"math"
+	"testing"
+)
+
+func TestVectorIndexSearch(t *testing.T) {
+	ix := NewVectorIndex("m", 2)
+	ix.mu.Lock()
+	ix.upsertLocked(IndexedTask{TaskID: "east", AgentID: "a", Mode: "beacon"}, []float32{2, 0}, "m", 2)
+	ix.upsertLocked(IndexedTask{TaskID: "north", AgentID: "b", Mode: "beacon"}, []float32{0, 3}, "m", 2)
+	ix.upsertLocked(IndexedTask{TaskID: "diag", AgentID: "c", Mode: "radar"}, []float32{1, 1}, "m", 2)
+	ix.mu.Unlock()
+
+	tests := []struct {
+		name     string
+		k        int
+		minScore float64
+		filter   func(IndexedTask) bool
+		want     []string
+	}{
+		{"all, best first", 0, -1, nil, []string{"east", "diag", "north"}},
+		{"top k", 1, -1, nil, []string{"east"}},
+		{"min score", 0, 0.5, nil, []string{"east", "diag"}},
+		{"filter before ranking", 1, -1, func(t IndexedTask) bool { return t.Mode == "radar" }, []string{"diag"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits, err := ix.Search([]float32{1, 0.1}, tt.k, tt.minScore, tt.filter)
+			if err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+			var got []string
+			for _, h := range hits {
+				got = append(got, h.TaskID)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("hits = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("hits = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestVectorIndexIncompatibleRows(t *testing.T) {
+	tests := []struct {
+		name       string
+		vec        []float32
+		model      string
+		dimensions int
+	}{
+		{"other model", []float32{1, 0, 0}, "other", 3},
+		{"other recorded dimensions", []float32{1, 0}, "m", 2},
+		// A row whose recorded dimensions do not describe its vector.
+		{"vector length differs from record", []float32{1, 0}, "m", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ix := NewVectorIndex("m", 3)
+			ix.mu.Lock()
+			ix.upsertLocked(IndexedTask{TaskID: "ok"}, []float32{1, 0, 0}, "m", 3)
+			ix.upsertLocked(IndexedTask{TaskID: "bad"}, tt.vec, tt.model, tt.dimensions)
+			ix.mu.Unlock()
+
+			if ix.Len() != 1 || ix.Incompatible() != 1 {
+				t.Fatalf("Len, Incompatible = %d, %d; want 1, 1", ix.Len(), ix.Incompatible())
+			}
+			if _, ok := ix.Vector("bad"); ok {
+				t.Error("incompatible vector is searchable")
+			}
+			hits, err := ix.Search([]float32{1, 0, 0}, 0, -1, nil)
+			if err != nil || len(hits) != 1 || hits[0].TaskID != "ok" {
+				t.Errorf("Search = %v, %v; want only the compatible task", hits, err)
+			}
+
+			// Re-embedding with the current model makes it searchable again.
+			ix.mu.Lock()
+			ix.upsertLocked(IndexedTask{TaskID: "bad"}, []float32{0, 1, 0}, "m", 3)
+			ix.mu.Unlock()
+			if ix.Len() != 2 || ix.Incompatible() != 0 {
+				t.Errorf("after re-embedding Len, Incompatible = %d, %d; want 2, 0", ix.Len(), ix.Incompatible())
+			}
+		})
+	}
+}
+
+func TestVectorIndexRejectsQueryOfWrongSize(t *testing.T) {
+	ix := NewVectorIndex("m", 3)
+	if _, err := ix.Search([]float32{1, 0}, 0, 0, nil); err == nil {
+		t.Error("Search accepted a 2-dimensional query on a 3-dimensional index")
+	}
+	if _, ok := ix.Score([]float32{1, 0}, "any"); ok {
+		t.Error("Score accepted a query of the wrong size")
+	}
+}
+
+func TestVectorIndexRemoveKeepsOthersAddressable(t *testing.T) {
+	ix := NewVectorIndex("m", 2)
+	ix.mu.Lock()
+	ix.upsertLocked(IndexedTask{TaskID: "a"}, []float32{1, 0}, "m", 2)
+	ix.upsertLocked(IndexedTask{TaskID: "b"}, []float32{0, 1}, "m", 2)
+	ix.upsertLocked(IndexedTask{TaskID: "c"}, []float32{1, 1}, "m", 2)
+	ix.mu.Unlock()
+
+	ix.Remove("a")
+	ix.Remove("missing")
+
+	if ix.Len() != 2 {
+		t.Fatalf("Len = %d, want 2", ix.Len())
+	}
+	// "c" was moved into "a"'s slot; its vector must have moved with it.
+	if sim, ok := ix.Similarity("b", "c"); !ok || math.Abs(sim-math.Sqrt2/2) > 1e-6 {
+		t.Errorf("Similarity(b, c) = %v, %v; want %v", sim, ok, math.Sqrt2/2)
+	}
+}
+
+func TestSaveTaskEmbeddingRecordsVectorLength(t *testing.T) {
+	database := openTestDB(t)
+	insertTestAgent(t, database, "agent")
+	insertTestTask(t, database, "task", "agent", "beacon", "general", "t", `["go"]`)
+	index := NewVectorIndex("m", 3)
+
+	// A provider ignoring the configured size returns 4 floats; the row must say so.
+	if err := SaveTaskEmbedding(database, index, "task", []float32{1, 0, 0, 0}, "m"); err != nil {
+		t.Fatalf("SaveTaskEmbedding: %v", err)
+	}
+	var dims int
+	if err := database.QueryRow("SELECT dimensions FROM task_embeddings WHERE task_id = 'task'").Scan(&dims); err != nil {
+		t.Fatal(err)
+	}
+	if dims != 4 {
+		t.Errorf("stored dimensions = %d, want 4", dims)
+	}
+	if index.Incompatible() != 1 {
+		t.Errorf("Incompatible = %d, want 1", index.Incompatible())
+	}
+}
@@ -18,22 +18,52 @@ type Agent struct {
 
 // Task represents a task registered by an agent.
 type Task struct {
-	ID        string `json:"id"`
-	AgentID   string `json:"agent_id"`
-	TaskID    string `json:"task_id"`
-	Mode      string `json:"mode"`
-	Type      string `json:"type"`
-	Title     string `json:"title"`
-	Keywords  string `json:"keywords"`
-	Status    string `json:"status"`
-	CreatedAt string `json:"created_at"`
-	UpdatedAt string `json:"updated_at"`
+	ID              string   `json:"id"`
+	AgentID         string   `json:"agent_id"`
+	TaskID          string   `json:"task_id"`
+	Mode            string   `json:"mode"`
+	Type            string   `json:"type"`
+	Title           string   `json:"title"`
+	Keywords        string   `json:"keywords"`
+	Attributes      string   `json:"attributes"`
+	Status          string   `json:"status"`
+	Standing        bool     `json:"standing"`
+	StandingFilters string   `json:"standing_filters"`
+	Latitude        *float64 `json:"latitude,omitempty"`
+	Longitude       *float64 `json:"longitude,omitempty"`
+	RadiusKm        float64  `json:"radius_km,omitempty"`
+	Place           string   `json:"place,omitempty"`
+	CreatedAt       string   `json:"created_at"`
+	UpdatedAt       string   `json:"updated_at"`
 }
 
-// TaskEmbedding stores the vector embedding for a task's keywords.
+// TaskEmbedding stores the vector embedding for a task's keywords, along with
+// the model and dimensions it was produced with.
 type TaskEmbedding struct {
-	TaskID    string `json:"task_id"`
-	Embedding []byte `json:"-"`
+	TaskID     string `json:"task_id"`
+	Embedding  []byte `json:"-"`
+	Model      string `json:"model"`
+	Dimensions int    `json:"dimensions"`
+}
+
+// EmbeddingQueueEntry is a task whose embedding failed and is waiting for a retry.
+type EmbeddingQueueEntry struct {
+	TaskID        string `json:"task_id"`
+	Attempts      int    `json:"attempts"`
+	LastError     string `json:"last_error"`
+	NextAttemptAt string `json:"next_attempt_at"`
+	CreatedAt     string `json:"created_at"`
+}
+
+// EmbeddingCacheEntry is a content-addressed embedding, keyed by a hash of
+// model, dimensions and normalized input text.
+type EmbeddingCacheEntry struct {
+	CacheKey   string `json:"cache_key"`
+	Model      string `json:"model"`
+	Dimensions int    `json:"dimensions"`
+	Embedding  []byte `json:"-"`
+	CreatedAt  string `json:"created_at"`
+	LastUsedAt string `json:"last_used_at"`
 }
 
 // Conversation represents a conversation between two agents about matching tasks.
@@ -44,8 +74,16 @@ type Conversation struct {
 	InitiatorTask  string `json:"initiator_task"`
 	TargetTask     string `json:"target_task"`
 	State          string `json:"state"`
-	CreatedAt      string `json:"created_at"`
-	UpdatedAt      string `json:"updated_at"`
+	DeclineReason  string `json:"decline_reason"`
+	// Round is the matching protocol round (1-3). PendingTransition is an escalation
+	// requested by TransitionRequestedBy that the other participant has not confirmed.
+	Round                 int             `json:"round"`
+	PendingTransition     string          `json:"pending_transition"`
+	TransitionRequestedBy string          `json:"transition_requested_by"`
+	ContactsReleasedAt    sql.NullString  `json:"contacts_released_at"`
+	MatchScore            sql.NullFloat64 `json:"match_score"`
+	CreatedAt             string          `json:"created_at"`
+	UpdatedAt             string          `json:"updated_at"`
 }
 
 // MessageQueue holds messages that are pending delivery to an agent.
@@ -68,6 +106,74 @@ type Report struct {
 	CreatedAt  string `json:"created_at"`
 }
 
+// MatchNotification is a new_match notification queued for a standing radar search.
+type MatchNotification struct {
+	ID           string         `json:"id"`
+	AgentID      string         `json:"agent_id"`
+	TaskID       string         `json:"task_id"`
+	MatchAgentID string         `json:"match_agent_id"`
+	MatchTaskID  string         `json:"match_task_id"`
+	Score        float64        `json:"score"`
+	CreatedAt    string         `json:"created_at"`
+	DeliveredAt  sql.NullString `json:"delivered_at"`
+}
+
+// ConversationNotification tells a participant about a change to a conversation made
+// by the other side, such as its request being accepted or declined. Delivered once.
+type ConversationNotification struct {
+	ID             string         `json:"id"`
+	ConversationID string         `json:"conversation_id"`
+	AgentID        string         `json:"agent_id"`
+	FromAgentID    string         `json:"from_agent_id"`
+	Type           string         `json:"type"`
+	Message        string         `json:"message"`
+	Reason         string         `json:"reason"`
+	Payload        []byte         `json:"-"` // encrypted contact card, cleared on delivery
+	CreatedAt      string         `json:"created_at"`
+	DeliveredAt    sql.NullString `json:"delivered_at"`
+}
+
+// ConversationTransition is one entry of a conversation's history: a state change
+// (FromState != ToState) or a round escalation. Actor is an agent ID or "system".
+type ConversationTransition struct {
+	ID             string `json:"id"`
+	ConversationID string `json:"conversation_id"`
+	Event          string `json:"event"`
+	FromState      string `json:"from_state"`
+	ToState        string `json:"to_state"`
+	FromRound      int    `json:"from_round"`
+	ToRound        int    `json:"to_round"`
+	Actor          string `json:"actor"`
+	Reason         string `json:"reason"`
+	CreatedAt      string `json:"created_at"`
+}
+
+// ContactCard is an agent's encrypted contact details, shared only through a
+// mutually consented exchange. TaskID is empty for the agent's default card.
+type ContactCard struct {
+	ID         string `json:"id"`
+	AgentID    string `json:"agent_id"`
+	TaskID     string `json:"task_id"`
+	Ciphertext []byte `json:"-"`
+	CreatedAt  string `json:"created_at"`
+	UpdatedAt  string `json:"updated_at"`
+}
+
+// ContactConsent records one participant's consent to exchange contact cards.
+type ContactConsent struct {
+	ConversationID string `json:"conversation_id"`
+	AgentID        string `json:"agent_id"`
+	Ciphertext     []byte `json:"-"`
+	CreatedAt      string `json:"created_at"`
+}
+
+// AgentBlock records that one agent does not want to be matched with another.
+type AgentBlock struct {
+	BlockerID string `json:"blocker_id"`
+	BlockedID string `json:"blocked_id"`
+	CreatedAt string `json:"created_at"`
+}
+
 // RegistrationLimit tracks registration attempts per IP+MAC combination.
 type RegistrationLimit struct {
 	IPMACHash     string `json:"ip_mac_hash"`
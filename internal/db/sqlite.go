@@ -18,7 +18,10 @@ func InitDB(path string) (*sql.DB, error) {
 		return nil, fmt.Errorf("failed to create data directory %s: %w", dir, err)
 	}
 
-	db, err := sql.Open("sqlite", path)
+	// Transactions take the write lock up front (BEGIN IMMEDIATE), so a transaction
+	// that reads and then writes cannot have its reads invalidated by another
+	// writer. Writers wait up to 5s for the lock instead of failing at once.
+	db, err := sql.Open("sqlite", dataSourceName(path))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -48,6 +51,16 @@ func InitDB(path string) (*sql.DB, error) {
 	return db, nil
 }
 
+// dataSourceName adds the connection options InitDB needs to path, which may
+// already carry query parameters of its own.
+func dataSourceName(path string) string {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return path + sep + "_txlock=immediate&_pragma=busy_timeout(5000)"
+}
+
 // CreateTables creates all required tables if they do not already exist.
 func CreateTables(db *sql.DB) error {
 	statements := []string{
@@ -126,6 +139,130 @@ func CreateTables(db *sql.DB) error {
 			last_reset_date TEXT NOT NULL
 		)`,
 
+		`CREATE TABLE IF NOT EXISTS match_notifications (
+			id TEXT PRIMARY KEY,
+			agent_id TEXT NOT NULL,
+			task_id TEXT NOT NULL,
+			match_agent_id TEXT NOT NULL,
+			match_task_id TEXT NOT NULL,
+			score REAL NOT NULL,
+			created_at TEXT NOT NULL,
+			delivered_at TEXT,
+			FOREIGN KEY (agent_id) REFERENCES agents(id),
+			FOREIGN KEY (task_id) REFERENCES tasks(id),
+			FOREIGN KEY (match_task_id) REFERENCES tasks(id)
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS conversation_notifications (
+			id TEXT PRIMARY KEY,
+			conversation_id TEXT NOT NULL,
+			agent_id TEXT NOT NULL,
+			from_agent_id TEXT NOT NULL,
+			type TEXT NOT NULL,
+			message TEXT NOT NULL,
+			reason TEXT NOT NULL DEFAULT '',
+			created_at TEXT NOT NULL,
+			delivered_at TEXT,
+			FOREIGN KEY (conversation_id) REFERENCES conversations(id),
+			FOREIGN KEY (agent_id) REFERENCES agents(id),
+			FOREIGN KEY (from_agent_id) REFERENCES agents(id)
+		)`,
+
+		// Contact cards are AES-GCM encrypted. task_id is '' for an agent's default card.
+		`CREATE TABLE IF NOT EXISTS contact_cards (
+			id TEXT PRIMARY KEY,
+			agent_id TEXT NOT NULL,
+			task_id TEXT NOT NULL DEFAULT '',
+			ciphertext BLOB NOT NULL,
+			created_at TEXT NOT NULL,
+			updated_at TEXT NOT NULL,
+			FOREIGN KEY (agent_id) REFERENCES agents(id)
+		)`,
+
+		// Consent to exchange contact cards, with a snapshot of the consenting
+		// agent's card that is cleared once the cards are released.
+		`CREATE TABLE IF NOT EXISTS contact_consents (
+			conversation_id TEXT NOT NULL,
+			agent_id TEXT NOT NULL,
+			ciphertext BLOB,
+			created_at TEXT NOT NULL,
+			PRIMARY KEY (conversation_id, agent_id),
+			FOREIGN KEY (conversation_id) REFERENCES conversations(id),
+			FOREIGN KEY (agent_id) REFERENCES agents(id)
+		)`,
+
+		// Conversation history: every state and round transition, oldest first.
+		`CREATE TABLE IF NOT EXISTS conversation_transitions (
+			id TEXT PRIMARY KEY,
+			conversation_id TEXT NOT NULL,
+			event TEXT NOT NULL,
+			from_state TEXT NOT NULL,
+			to_state TEXT NOT NULL,
+			from_round INTEGER NOT NULL,
+			to_round INTEGER NOT NULL,
+			actor TEXT NOT NULL,
+			reason TEXT NOT NULL DEFAULT '',
+			created_at TEXT NOT NULL,
+			FOREIGN KEY (conversation_id) REFERENCES conversations(id)
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS agent_blocks (
+			blocker_id TEXT NOT NULL,
+			blocked_id TEXT NOT NULL,
+			created_at TEXT NOT NULL,
+			PRIMARY KEY (blocker_id, blocked_id),
+			FOREIGN KEY (blocker_id) REFERENCES agents(id),
+			FOREIGN KEY (blocked_id) REFERENCES agents(id)
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS embedding_cache (
+			cache_key TEXT PRIMARY KEY,
+			model TEXT NOT NULL,
+			dimensions INTEGER NOT NULL,
+			embedding BLOB NOT NULL,
+			created_at TEXT NOT NULL,
+			last_used_at TEXT NOT NULL
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS embedding_queue (
+			task_id TEXT PRIMARY KEY,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			last_error TEXT NOT NULL DEFAULT '',
+			next_attempt_at TEXT NOT NULL,
+			created_at TEXT NOT NULL,
+			FOREIGN KEY (task_id) REFERENCES tasks(id)
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS standing_match_queue (
+			task_id TEXT NOT NULL,
+			job TEXT NOT NULL,
+			created_at TEXT NOT NULL,
+			PRIMARY KEY (task_id, job),
+			FOREIGN KEY (task_id) REFERENCES tasks(id)
+		)`,
+
+		// Full-text index over task titles and keywords for lexical matching.
+		// Kept in sync with tasks by the triggers below.
+		`CREATE VIRTUAL TABLE IF NOT EXISTS task_fts USING fts5(
+			task_id UNINDEXED,
+			title,
+			keywords,
+			tokenize = 'unicode61 remove_diacritics 2'
+		)`,
+
+		`CREATE TRIGGER IF NOT EXISTS tasks_fts_insert AFTER INSERT ON tasks BEGIN
+			INSERT INTO task_fts (task_id, title, keywords) VALUES (new.id, new.title, new.keywords);
+		END`,
+
+		`CREATE TRIGGER IF NOT EXISTS tasks_fts_update AFTER UPDATE OF title, keywords ON tasks BEGIN
+			DELETE FROM task_fts WHERE task_id = old.id;
+			INSERT INTO task_fts (task_id, title, keywords) VALUES (new.id, new.title, new.keywords);
+		END`,
+
+		`CREATE TRIGGER IF NOT EXISTS tasks_fts_delete AFTER DELETE ON tasks BEGIN
+			DELETE FROM task_fts WHERE task_id = old.id;
+		END`,
+
 		// Indexes for common queries.
 		`CREATE INDEX IF NOT EXISTS idx_tasks_agent_id ON tasks(agent_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_tasks_status ON tasks(status)`,
@@ -135,6 +272,15 @@ func CreateTables(db *sql.DB) error {
 		`CREATE INDEX IF NOT EXISTS idx_conversations_initiator ON conversations(initiator_agent)`,
 		`CREATE INDEX IF NOT EXISTS idx_conversations_target ON conversations(target_agent)`,
 		`CREATE INDEX IF NOT EXISTS idx_tasks_agent_task ON tasks(agent_id, task_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_conversations_initiator_task ON conversations(initiator_task)`,
+		`CREATE INDEX IF NOT EXISTS idx_conversations_target_task ON conversations(target_task)`,
+		`CREATE INDEX IF NOT EXISTS idx_agent_blocks_blocked ON agent_blocks(blocked_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_match_notifications_agent ON match_notifications(agent_id, delivered_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_conversation_notifications_agent ON conversation_notifications(agent_id, delivered_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_conversation_transitions_conversation ON conversation_transitions(conversation_id, created_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_contact_cards_agent ON contact_cards(agent_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_embedding_cache_last_used ON embedding_cache(last_used_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_embedding_queue_next_attempt ON embedding_queue(next_attempt_at)`,
 	}
 
 	for _, stmt := range statements {
@@ -152,6 +298,35 @@ func RunMigrations(db *sql.DB) error {
 	migrations := []string{
 		`ALTER TABLE tasks ADD COLUMN updated_at TEXT NOT NULL DEFAULT ''`,
 		`ALTER TABLE conversations ADD COLUMN last_message_at TEXT`,
+		// Optional reason given by the target when declining a conversation request.
+		`ALTER TABLE conversations ADD COLUMN decline_reason TEXT NOT NULL DEFAULT ''`,
+		// Matching protocol round (1-3) and an escalation awaiting the other side's confirmation.
+		`ALTER TABLE conversations ADD COLUMN round INTEGER NOT NULL DEFAULT 1`,
+		`ALTER TABLE conversations ADD COLUMN pending_transition TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE conversations ADD COLUMN transition_requested_by TEXT NOT NULL DEFAULT ''`,
+		// Set once both participants consented and their contact cards were released.
+		`ALTER TABLE conversations ADD COLUMN contacts_released_at TEXT`,
+		// Similarity of the two tasks when the conversation was opened; NULL if either was not indexed.
+		`ALTER TABLE conversations ADD COLUMN match_score REAL`,
+		// Encrypted contact card carried by contact_card notifications until delivery.
+		`ALTER TABLE conversation_notifications ADD COLUMN payload BLOB`,
+		// Radar tasks flagged as standing searches get new_match notifications.
+		`ALTER TABLE tasks ADD COLUMN standing INTEGER NOT NULL DEFAULT 0`,
+		// Structured attributes (JSON object) validated against a per-type schema.
+		`ALTER TABLE tasks ADD COLUMN attributes TEXT NOT NULL DEFAULT '{}'`,
+		// Optional task location for geo-radius matching.
+		`ALTER TABLE tasks ADD COLUMN latitude REAL`,
+		`ALTER TABLE tasks ADD COLUMN longitude REAL`,
+		`ALTER TABLE tasks ADD COLUMN radius_km REAL NOT NULL DEFAULT 0`,
+		`ALTER TABLE tasks ADD COLUMN place TEXT NOT NULL DEFAULT ''`,
+		// Attribute filters a standing radar search applies to new beacons, as in a scan.
+		`ALTER TABLE tasks ADD COLUMN standing_filters TEXT NOT NULL DEFAULT '{}'`,
+		// Rows from before model tracking get an empty model and are re-embedded in the background.
+		`ALTER TABLE task_embeddings ADD COLUMN model TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE task_embeddings ADD COLUMN dimensions INTEGER NOT NULL DEFAULT 0`,
+		// Backfill the full-text index for tasks created before it existed.
+		`INSERT INTO task_fts (task_id, title, keywords)
+		 SELECT id, title, keywords FROM tasks WHERE id NOT IN (SELECT task_id FROM task_fts)`,
 	}
 
 	for _, m := range migrations {
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDataSourceName(t *testing.T) {
+	tests := []struct {
+		path, want string
+	}{
+		{"data/agentsocial.db", "data/agentsocial.db?_txlock=immediate&_pragma=busy_timeout(5000)"},
+		{"file:data.db?cache=shared", "file:data.db?cache=shared&_txlock=immediate&_pragma=busy_timeout(5000)"},
+	}
+	for _, tt := range tests {
+		if got := dataSourceName(tt.path); got != tt.want {
+			t.Errorf("dataSourceName(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestInitDBPathWithQuery(t *testing.T) {
+	dir := t.TempDir()
+	database, err := InitDB(filepath.Join(dir, "test.db") + "?_pragma=synchronous(NORMAL)")
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	defer database.Close()
+
+	var synchronous, busyTimeout int
+	if err := database.QueryRow("PRAGMA synchronous").Scan(&synchronous); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.QueryRow("PRAGMA busy_timeout").Scan(&busyTimeout); err != nil {
+		t.Fatal(err)
+	}
+	// NORMAL is 1; both the path's own and InitDB's options apply.
+	if synchronous != 1 || busyTimeout != 5000 {
+		t.Errorf("synchronous, busy_timeout = %d, %d; want 1, 5000", synchronous, busyTimeout)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "test.db")); err != nil {
+		t.Errorf("database file not created at the path without its query: %v", err)
+	}
+}